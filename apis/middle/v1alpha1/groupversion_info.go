@@ -19,6 +19,7 @@ limitations under the License.
 //+groupName=middle.alauda.cn
 //+kubebuilder:rbac:groups="",resources=pods;deployments;endpoints;persistentvolumeclaims;configmaps;services;events;namespaces;secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps,resources=deployments;replicasets;statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 
 package v1alpha1
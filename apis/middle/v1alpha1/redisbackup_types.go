@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,17 +26,66 @@ import (
 
 // RedisBackupSpec defines the desired state of RedisBackup
 type RedisBackupSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
-
-	// Foo is an example field of RedisBackup. Edit redisbackup_types.go to remove/update
-	Foo string `json:"foo,omitempty"`
+	// RedisFailoverName is the RedisFailover in the same namespace to back up
+	RedisFailoverName string `json:"redisFailoverName"`
+	// SourcePod pins the backup to a given redis pod. When empty an in-sync replica is used,
+	// falling back to the master
+	SourcePod       string             `json:"sourcePod,omitempty"`
+	Image           string             `json:"image,omitempty"`
+	ImagePullPolicy corev1.PullPolicy  `json:"imagePullPolicy,omitempty"`
+	Storage         RedisBackupStorage `json:"storage"`
+	// Encryption encrypts the RDB before it is written to the storage
+	Encryption *RedisBackupEncryption `json:"encryption,omitempty"`
 }
 
+// RedisBackupPhase is the lifecycle phase of a RedisBackup
+type RedisBackupPhase string
+
+const (
+	RedisBackupPhasePending   RedisBackupPhase = "Pending"
+	RedisBackupPhaseRunning   RedisBackupPhase = "Running"
+	RedisBackupPhaseSucceeded RedisBackupPhase = "Succeeded"
+	RedisBackupPhaseFailed    RedisBackupPhase = "Failed"
+)
+
+// RedisBackupSourceRole is the role of the redis pod a backup is taken from
+type RedisBackupSourceRole string
+
+const (
+	RedisBackupSourceMaster  RedisBackupSourceRole = "master"
+	RedisBackupSourceReplica RedisBackupSourceRole = "replica"
+)
+
 // RedisBackupStatus defines the observed state of RedisBackup
 type RedisBackupStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Pending, Running, Succeeded, Failed
+	Phase   RedisBackupPhase `json:"phase,omitempty"`
+	Message string           `json:"message,omitempty"`
+	// SourcePod is the redis pod the RDB was taken from
+	SourcePod string `json:"sourcePod,omitempty"`
+	// SourceRole is the replication role of SourcePod when the backup started, master or replica
+	SourceRole     RedisBackupSourceRole `json:"sourceRole,omitempty"`
+	JobName        string                `json:"jobName,omitempty"`
+	StartTime      *metav1.Time          `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time          `json:"completionTime,omitempty"`
+	// Size of the RDB artifact in bytes
+	Size int64 `json:"size,omitempty"`
+	// RDBVersion, RedisVersion and Keys are read from the artifact once its header and checksum are verified
+	RDBVersion   int32  `json:"rdbVersion,omitempty"`
+	RedisVersion string `json:"redisVersion,omitempty"`
+	Keys         int64  `json:"keys,omitempty"`
+	// Keyspace is the number of keys per database reported by the source pod when the save completed
+	Keyspace map[string]int64 `json:"keyspace,omitempty"`
+	// EncryptionKeyID is the key of the encryption Secret the artifact is encrypted with
+	EncryptionKeyID string `json:"encryptionKeyID,omitempty"`
+	// PersistentVolumeClaim and Path locate the artifact, Path is the object key when the backup is on S3
+	PersistentVolumeClaim string `json:"persistentVolumeClaim,omitempty"`
+	Path                  string `json:"path,omitempty"`
+}
+
+// IsFinished reports whether the backup reached a terminal phase
+func (s *RedisBackupStatus) IsFinished() bool {
+	return s.Phase == RedisBackupPhaseSucceeded || s.Phase == RedisBackupPhaseFailed
 }
 
 //+kubebuilder:object:root=true
@@ -31,6 +31,41 @@ type RedisFailoverSpec struct {
 	Sentinel       SentinelSettings `json:"sentinel,omitempty"`
 	Auth           AuthSettings     `json:"auth,omitempty"`
 	LabelWhitelist []string         `json:"labelWhitelist,omitempty"`
+	// Source creates the instance pre-populated with the data of another instance or of a backup
+	Source *RedisFailoverSource `json:"source,omitempty"`
+	// Expose makes every redis and sentinel pod reachable from outside the cluster
+	Expose *RedisFailoverExpose `json:"expose,omitempty"`
+}
+
+// RedisFailoverExpose gives every redis and sentinel pod a Service of its own. The pods announce the address
+// of their Service, so the sentinels hand out addresses clients outside the cluster can reach, before and
+// after a failover. Announcing the sentinels requires sentinels of redis 6.2 or later: a sentinel image tagged
+// with an older version is rejected, the default one included, and sentinels of an image tagged without a
+// version keep announcing their pod IP if older, which a SentinelAnnounceUnsupported event reports once.
+type RedisFailoverExpose struct {
+	// Type of the Services, NodePort or LoadBalancer, defaults to NodePort.
+	// A NodePort Service is announced on the IP of the node running the pod.
+	Type corev1.ServiceType `json:"type,omitempty"`
+	// RedisNodePorts fixes the node port of the redis pods, by ordinal, they are allocated by kubernetes otherwise
+	RedisNodePorts []int32 `json:"redisNodePorts,omitempty"`
+	// SentinelNodePorts fixes the node port of the sentinels, they are allocated by kubernetes otherwise
+	SentinelNodePorts []int32 `json:"sentinelNodePorts,omitempty"`
+	// Annotations of the Services, to configure the load balancer for instance
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// RedisFailoverSource is the data a new instance is cloned from, exactly one of RedisFailover and Backup is set.
+// Like Restore it only seeds a new instance, and requires redis storage on a persistentVolumeClaim.
+type RedisFailoverSource struct {
+	// RedisFailover to clone, as namespace/name or name. A RedisBackup of it is taken in its namespace,
+	// the source instance is never touched beyond that snapshot.
+	RedisFailover string `json:"redisFailover,omitempty"`
+	// Backup to clone, the namespace/name or name of a succeeded RedisBackup
+	Backup string `json:"backup,omitempty"`
+	// Storage of the backup taken of RedisFailover, s3 is required to clone across namespaces
+	Storage RedisBackupStorage `json:"storage,omitempty"`
+	// Encryption of the backup taken of RedisFailover
+	Encryption *RedisBackupEncryption `json:"encryption,omitempty"`
 }
 
 // RedisCommandRename defines the specification of a "rename-command" configuration option
@@ -41,28 +76,34 @@ type RedisCommandRename struct {
 
 // RedisSettings defines the specification of the redis cluster
 type RedisSettings struct {
-	Image                string                        `json:"image,omitempty"`
-	ImagePullPolicy      corev1.PullPolicy             `json:"imagePullPolicy,omitempty"`
-	Replicas             int32                         `json:"replicas,omitempty"`
-	Resources            corev1.ResourceRequirements   `json:"resources,omitempty"`
-	ConfigConfigMap      string                        `json:"configConfigMap,omitempty"`
-	CustomConfig         map[string]string             `json:"customConfig,omitempty"`
-	CustomCommandRenames []RedisCommandRename          `json:"customCommandRenames,omitempty"`
-	Command              []string                      `json:"command,omitempty"`
-	ShutdownConfigMap    string                        `json:"shutdownConfigMap,omitempty"`
-	Storage              RedisStorage                  `json:"storage,omitempty"`
-	Exporter             RedisExporter                 `json:"exporter,omitempty"`
-	Affinity             *corev1.Affinity              `json:"affinity,omitempty"`
-	SecurityContext      *corev1.PodSecurityContext    `json:"securityContext,omitempty"`
-	ImagePullSecrets     []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
-	Tolerations          []corev1.Toleration           `json:"tolerations,omitempty"`
-	NodeSelector         map[string]string             `json:"nodeSelector,omitempty"`
-	PodAnnotations       map[string]string             `json:"podAnnotations,omitempty"`
-	ServiceAnnotations   map[string]string             `json:"serviceAnnotations,omitempty"`
-	HostNetwork          bool                          `json:"hostNetwork,omitempty"`
-	DNSPolicy            corev1.DNSPolicy              `json:"dnsPolicy,omitempty"`
-	Backup               RedisBackup                   `json:"backup,omitempty"`
-	Restore              RedisRestore                  `json:"restore,omitempty"`
+	Image                string                      `json:"image,omitempty"`
+	ImagePullPolicy      corev1.PullPolicy           `json:"imagePullPolicy,omitempty"`
+	Replicas             int32                       `json:"replicas,omitempty"`
+	Resources            corev1.ResourceRequirements `json:"resources,omitempty"`
+	ConfigConfigMap      string                      `json:"configConfigMap,omitempty"`
+	CustomConfig         map[string]string           `json:"customConfig,omitempty"`
+	CustomCommandRenames []RedisCommandRename        `json:"customCommandRenames,omitempty"`
+	// Command replaces "redis-server /redis/redis.conf --slaveof 127.0.0.1 6379", redis.conf is only loaded if
+	// the command passes it. ConfigConfigMap and CustomCommandRenames can't be set with it, Persistence and the
+	// settings of CustomConfig CONFIG SET accepts are applied once redis runs.
+	Command            []string                      `json:"command,omitempty"`
+	ShutdownConfigMap  string                        `json:"shutdownConfigMap,omitempty"`
+	Storage            RedisStorage                  `json:"storage,omitempty"`
+	Persistence        RedisPersistence              `json:"persistence,omitempty"`
+	Exporter           RedisExporter                 `json:"exporter,omitempty"`
+	Affinity           *corev1.Affinity              `json:"affinity,omitempty"`
+	SecurityContext    *corev1.PodSecurityContext    `json:"securityContext,omitempty"`
+	ImagePullSecrets   []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	Tolerations        []corev1.Toleration           `json:"tolerations,omitempty"`
+	NodeSelector       map[string]string             `json:"nodeSelector,omitempty"`
+	PodAnnotations     map[string]string             `json:"podAnnotations,omitempty"`
+	ServiceAnnotations map[string]string             `json:"serviceAnnotations,omitempty"`
+	HostNetwork        bool                          `json:"hostNetwork,omitempty"`
+	DNSPolicy          corev1.DNSPolicy              `json:"dnsPolicy,omitempty"`
+	Backup             RedisBackupSetting            `json:"backup,omitempty"`
+	Restore            RedisRestore                  `json:"restore,omitempty"`
+	// SplitBrainPolicy defaults to auto
+	SplitBrainPolicy RedisSplitBrainPolicy `json:"splitBrainPolicy,omitempty"`
 }
 
 // SentinelSettings defines the specification of the sentinel cluster
@@ -83,6 +124,23 @@ type SentinelSettings struct {
 	Exporter           SentinelExporter              `json:"exporter,omitempty"`
 	HostNetwork        bool                          `json:"hostNetwork,omitempty"`
 	DNSPolicy          corev1.DNSPolicy              `json:"dnsPolicy,omitempty"`
+	// Quorum of sentinels agreeing the master is down to fail it over, defaults to a majority of Replicas
+	Quorum int32 `json:"quorum,omitempty"`
+	// DownAfterMilliseconds the master stays unreachable before a sentinel considers it down, defaults to 5000
+	DownAfterMilliseconds int32 `json:"downAfterMilliseconds,omitempty"`
+	// FailoverTimeout in milliseconds, defaults to 3000
+	FailoverTimeout int32 `json:"failoverTimeout,omitempty"`
+	// ParallelSyncs is the number of replicas resyncing with the new master at once after a failover, defaults to 2
+	ParallelSyncs int32 `json:"parallelSyncs,omitempty"`
+	// NotificationScript and ClientReconfigScript are paths of executables in the sentinel image
+	NotificationScript   string `json:"notificationScript,omitempty"`
+	ClientReconfigScript string `json:"clientReconfigScript,omitempty"`
+	// MasterName the sentinels monitor the master under, defaults to mymaster, or to the name of the
+	// RedisFailover when its sentinels are shared
+	MasterName string `json:"masterName,omitempty"`
+	// Shared names the RedisFailover of the namespace whose sentinels monitor this instance, under its own
+	// MasterName. The instance then runs no sentinels and only MasterName, Quorum and the tuning fields apply.
+	Shared string `json:"shared,omitempty"`
 }
 
 // AuthSettings contains settings about auth
@@ -107,22 +165,77 @@ type SentinelExporter struct {
 // RedisStorage defines the structure used to store the Redis Data
 
 type RedisStorage struct {
+	// KeepAfterDeletion orphans the redis volumes when the RedisFailover is deleted, they are deleted otherwise.
+	// A RedisFailover created again with the same name reattaches them.
 	KeepAfterDeletion bool                         `json:"keepAfterDeletion,omitempty"`
 	EmptyDir          *corev1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
 
 	PersistentVolumeClaim *corev1.PersistentVolumeClaim `json:"persistentVolumeClaim,omitempty"`
 }
 
-// RedisBackup defines the structure used to backup the Redis Data
+// RedisPersistenceMode selects how redis persists its data
+// +kubebuilder:validation:Enum=rdb;aof;both;none
+type RedisPersistenceMode string
+
+const (
+	RedisPersistenceModeRDB  RedisPersistenceMode = "rdb"
+	RedisPersistenceModeAOF  RedisPersistenceMode = "aof"
+	RedisPersistenceModeBoth RedisPersistenceMode = "both"
+	// RedisPersistenceModeNone runs redis as a pure cache
+	RedisPersistenceModeNone RedisPersistenceMode = "none"
+)
+
+// RedisSplitBrainPolicy selects what the operator does when several redis pods are masters
+// +kubebuilder:validation:Enum=auto;manual
+type RedisSplitBrainPolicy string
+
+const (
+	// RedisSplitBrainPolicyAuto keeps the master elected from the sentinels and the replication offsets
+	// and turns the others into its replicas, discarding the writes they took alone
+	RedisSplitBrainPolicyAuto RedisSplitBrainPolicy = "auto"
+	// RedisSplitBrainPolicyManual stops healing the instance until the extra masters are fixed by hand
+	RedisSplitBrainPolicyManual RedisSplitBrainPolicy = "manual"
+)
+
+// RedisPersistence defines the save and appendonly settings of redis.conf, which can't be set in
+// CustomConfig. Changes are applied to the running pods. The aof and both modes can only be set once
+// a restore from Restore.BackupName or Source is done, redis would not load the restored RDB.
+type RedisPersistence struct {
+	// Mode defaults to rdb
+	Mode RedisPersistenceMode `json:"mode,omitempty"`
+	// Save points of the RDB snapshots of the rdb and both modes, defaults to 900 1 and 300 10
+	Save []RedisSavePoint `json:"save,omitempty"`
+	// AppendFsync is the fsync policy of the aof and both modes, defaults to everysec
+	// +kubebuilder:validation:Enum=always;everysec;no
+	AppendFsync string `json:"appendFsync,omitempty"`
+}
+
+// RedisSavePoint snapshots the data after Seconds when at least Changes keys changed
+type RedisSavePoint struct {
+	Seconds int32 `json:"seconds"`
+	Changes int32 `json:"changes"`
+}
+
+// RedisBackupSetting defines the structure used to backup the Redis Data
 type RedisBackupSetting struct {
-	Image    string     `json:"image,omitempty"`
-	Schedule []Schedule `json:"schedule,omitempty"`
+	Image string `json:"image,omitempty"`
+	// Encryption encrypts the backups of every schedule and the final backup
+	Encryption *RedisBackupEncryption `json:"encryption,omitempty"`
+	Schedule   []Schedule             `json:"schedule,omitempty"`
+	// FinalBackup takes a backup on this storage before the RedisFailover is deleted, the backup is kept
+	// after the deletion. The deletion waits for it to succeed, remove it to delete a broken instance.
+	FinalBackup *RedisBackupStorage `json:"finalBackup,omitempty"`
 }
 
+// Schedule creates a RedisBackup on every tick of a cron expression and keeps the latest Keep successful ones
 type Schedule struct {
-	Name              string             `json:"name"`
-	Schedule          string             `json:"schedule"`
-	Keep              int32              `json:"keep"`
+	// Name of the schedule, a DNS label naming and labelling its backups
+	Name string `json:"name"`
+	// Schedule is a standard five fields cron expression, e.g. "0 3 * * *". Missed backups are taken once,
+	// at most a day late.
+	Schedule string `json:"schedule"`
+	Keep     int32  `json:"keep"`
+	// KeepAfterDeletion keeps the backups of this schedule when the RedisFailover is deleted
 	KeepAfterDeletion bool               `json:"keepAfterDeletion,omitempty"`
 	Storage           RedisBackupStorage `json:"storage"`
 }
@@ -130,25 +243,112 @@ type Schedule struct {
 type RedisBackupStorage struct {
 	StorageClassName string            `json:"storageClassName,omitempty"`
 	Size             resource.Quantity `json:"size,omitempty"`
+	// S3 uploads the backup to an S3-compatible object storage instead of a PersistentVolumeClaim
+	S3 *RedisBackupS3Storage `json:"s3,omitempty"`
 }
 
-// RedisBackup defines the structure used to restore the Redis Data
+// RedisBackupEncryption encrypts the backup with AES-256-CBC, its key derived with PBKDF2 from a passphrase.
+// Keys are rotated by adding a new KeyID to the Secret, the backups record the KeyID they are encrypted with
+// and the older keys must be kept as long as their backups are restorable.
+type RedisBackupEncryption struct {
+	// SecretName of the Secret, in the namespace of the backup, holding the passphrases keyed by key ID
+	SecretName string `json:"secretName"`
+	// KeyID is the key of the Secret holding the passphrase new backups are encrypted with
+	KeyID string `json:"keyID"`
+	// Image of the openssl cli encrypting and decrypting the backup
+	Image           string            `json:"image,omitempty"`
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+}
+
+// RedisBackupS3Storage locates the bucket backups are uploaded to. The objects are named
+// <prefix>/<namespace>/<name>/<timestamp>/dump.rdb[.enc] and are left in place when the RedisBackup is deleted.
+type RedisBackupS3Storage struct {
+	// Endpoint of the S3 API, e.g. https://s3.amazonaws.com or http://minio.minio:9000
+	Endpoint string `json:"endpoint"`
+	// Region of the bucket, defaults to us-east-1
+	Region string `json:"region,omitempty"`
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix,omitempty"`
+	// CredentialsSecret in the namespace of the backup holding the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY keys
+	CredentialsSecret string `json:"credentialsSecret"`
+	// Image of the aws cli transferring the backup
+	Image           string            `json:"image,omitempty"`
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+}
+
+// RedisRestore defines the structure used to restore the Redis Data
 type RedisRestore struct {
+	// Image used to stage the backup, defaults to the redis image
 	Image           string            `json:"image,omitempty"`
 	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
-	BackupName      string            `json:"backupName,omitempty"`
+	// BackupName is the succeeded RedisBackup, in the same namespace, the new instance is seeded from,
+	// downloading and decrypting it first when the backup sits on S3 or is encrypted. The restore runs once, when the instance is created,
+	// and requires redis storage on a persistentVolumeClaim.
+	BackupName string `json:"backupName,omitempty"`
 }
 
 // RedisStatus
 type RedisFailoverStatus struct {
-	Conditions []Condition `json:"conditions,omitempty"`
-	// The last time this condition was updated.
-	// Creating, Pending, Fail, Ready
-	Phase string `json:"phase,omitempty"`
-
+	// Conditions of types Ready, Available, Progressing, Degraded, Restored and Paused
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec the status was last reconciled for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Creating, Pending, Failed, Ready or Terminating
+	Phase Phase `json:"phase,omitempty"`
+
+	// Instance reports the pods of the StatefulSet and the sentinel Deployment
 	Instance RedisStatusInstance `json:"instance,omitempty"`
-	Master   RedisStatusMaster   `json:"master,omitempty"`
-	Version  string              `json:"version,omitempty"`
+	// Master is the redis pod found acting as master on the last check
+	Master RedisStatusMaster `json:"master,omitempty"`
+	// Version is the redis_version the master reports in INFO server
+	Version string `json:"version,omitempty"`
+	// SentinelVersion is the redis_version the sentinels report in INFO server, checked when the instance is exposed
+	SentinelVersion string `json:"sentinelVersion,omitempty"`
+	// Switchover reports the last switchover requested with the switchover annotation
+	Switchover *RedisSwitchoverStatus `json:"switchover,omitempty"`
+	// SentinelCommandRenames are the command renames the sentinels were last told, by lower case command name
+	SentinelCommandRenames map[string]string `json:"sentinelCommandRenames,omitempty"`
+	// PendingFailover is the failover an upgrade or a scale-down asked the sentinels for, until the master moves
+	PendingFailover *RedisPendingFailover `json:"pendingFailover,omitempty"`
+	// ExcludedReplicas are the redis pods the operator set a slave-priority of 0 on to keep a failover from
+	// promoting them, until their priority is restored
+	ExcludedReplicas []string `json:"excludedReplicas,omitempty"`
+}
+
+// RedisPendingFailover is a failover the operator asked the sentinels for
+type RedisPendingFailover struct {
+	// From is the master pod the failover moves the master off
+	From      string      `json:"from"`
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// RedisSwitchoverPhase is the progress of a switchover
+type RedisSwitchoverPhase string
+
+const (
+	// RedisSwitchoverPhasePending waits for the replicas to sync, or for an upgrade or a scale-down to end
+	RedisSwitchoverPhasePending   RedisSwitchoverPhase = "Pending"
+	RedisSwitchoverPhaseRunning   RedisSwitchoverPhase = "Running"
+	RedisSwitchoverPhaseSucceeded RedisSwitchoverPhase = "Succeeded"
+	RedisSwitchoverPhaseFailed    RedisSwitchoverPhase = "Failed"
+)
+
+// RedisSwitchoverStatus reports a switchover of the master
+type RedisSwitchoverStatus struct {
+	// RequestID is the ID given in the switchover annotation
+	RequestID string `json:"requestID"`
+	// Target is the redis pod requested as the new master, any replica when empty
+	Target string               `json:"target,omitempty"`
+	Phase  RedisSwitchoverPhase `json:"phase"`
+	// Message tells why the switchover waits or failed
+	Message string `json:"message,omitempty"`
+	// From is the master pod when the switchover started, Master the master pod once done
+	From           string       `json:"from,omitempty"`
+	Master         string       `json:"master,omitempty"`
+	StartTime      *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
 }
 
 type RedisStatusInstance struct {
@@ -170,9 +370,12 @@ type RedisStatusInstanceSentinel struct {
 }
 
 type RedisStatusMaster struct {
-	Name    string                  `json:"name"`
-	Status  RedisStatusMasterStatus `json:"status"`
-	Address string                  `json:"address"`
+	// Name of the master pod
+	Name string `json:"name"`
+	// Status is down when no single master could be found
+	Status RedisStatusMasterStatus `json:"status"`
+	// Address replicas and sentinels reach the master on
+	Address string `json:"address"`
 }
 
 type RedisStatusMasterStatus string
@@ -184,6 +387,12 @@ const (
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Master",type=string,JSONPath=`.status.master.address`
+//+kubebuilder:printcolumn:name="Redis",type=integer,JSONPath=`.status.instance.redis.ready`
+//+kubebuilder:printcolumn:name="Sentinel",type=integer,JSONPath=`.status.instance.sentinel.ready`
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // RedisFailover is the Schema for the redisfailovers API
 type RedisFailover struct {
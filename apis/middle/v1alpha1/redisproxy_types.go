@@ -54,8 +54,13 @@ type ProxyInfo struct {
 
 // RedisProxyStatus defines the observed state of RedisProxy
 type RedisProxyStatus struct {
-	Conditions []Condition `json:"conditions,omitempty"`
-	Version    string      `json:"version,omitempty"`
+	// Conditions of types Ready and Progressing
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec the status was last reconciled for
+	ObservedGeneration int64  `json:"observedGeneration,omitempty"`
+	Version            string `json:"version,omitempty"`
 }
 
 //+kubebuilder:object:root=true
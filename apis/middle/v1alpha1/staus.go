@@ -1,212 +1,343 @@
 package v1alpha1
 
 import (
-	corev1 "k8s.io/api/core/v1"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"sort"
-	"time"
 )
 
-// Phase of the RF status
+// Phase of the RF status. A new instance is Creating until its pods are first ready, then Ready
+// after every successful check. It is Pending while pods are not ready or being scaled or upgraded,
+// and Failed when a check fails, until the next successful one. It is Terminating once deleted.
 type Phase string
 
-// Condition saves the state information of the redis RedisFailover
-type Condition struct {
-	// Status of RedisFailover condition.
-	Type ConditionType `json:"type"`
-	// Status of the condition, one of True, False, Unknown.
-	Status corev1.ConditionStatus `json:"status"`
-	// The last time this condition was updated.
-	LastUpdateTime string      `json:"lastUpdateTime,omitempty"`
-	LastUpdateAt   metav1.Time `json:"-"`
-	// Last time the condition transitioned from one status to another.
-	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
-	// The reason for the condition's last transition.
-	Reason string `json:"reason,omitempty"`
-	// A human readable message indicating details about the transition.
-	Message string `json:"message,omitempty"`
-}
-
-// ConditionType defines the condition that the RF can have
-type ConditionType string
+const (
+	RedisFailoverPhaseCreating    Phase = "Creating"
+	RedisFailoverPhasePending     Phase = "Pending"
+	RedisFailoverPhaseReady       Phase = "Ready"
+	RedisFailoverPhaseFailed      Phase = "Failed"
+	RedisFailoverPhaseTerminating Phase = "Terminating"
+)
 
+// The condition types of RedisFailover and RedisProxy. Each type is always the same condition,
+// whichever was written last, and carries the generation it was observed at.
 const (
-	RedisFailoverConditionAvailable   ConditionType = "Available"
-	RedisFailoverConditionHealthy     ConditionType = "Healthy"
-	RedisFailoverConditionRunning                   = "Running"
-	RedisFailoverConditionCreating                  = "Creating"
-	RedisFailoverConditionRecovering                = "Recovering"
-	RedisFailoverConditionScaling                   = "Scaling"
-	RedisFailoverConditionScalingDown               = "ScalingDown"
-	RedisFailoverConditionUpgrading                 = "Upgrading"
-	RedisFailoverConditionUpdating                  = "Updating"
-	RedisFailoverConditionFailed                    = "Failed"
+	// ConditionReady is True when the last check of the instance passed
+	ConditionReady = "Ready"
+	// ConditionAvailable is True when a single master serves the instance
+	ConditionAvailable = "Available"
+	// ConditionProgressing is True while the instance is created, scaled, upgraded or torn down,
+	// the reason tells which and the message the step it is at
+	ConditionProgressing = "Progressing"
+	// ConditionDegraded is True when the last reconcile failed
+	ConditionDegraded = "Degraded"
+	// ConditionRestored records the restore of Restore.BackupName, once done or skipped
+	ConditionRestored = "Restored"
+	// ConditionPaused is True while the operator is paused or in maintenance on the instance, the
+	// message says who set it, the transition time since when
+	ConditionPaused = "Paused"
 )
 
-func (rf *RedisFailoverStatus) DescConditionsByTime() {
-	sort.Slice(rf.Conditions, func(i, j int) bool {
-		return rf.Conditions[i].LastUpdateAt.After(rf.Conditions[j].LastUpdateAt.Time)
-	})
+// The annotations pausing the operator on a RedisFailover or RedisProxy. Their value records who
+// set them and why, e.g. "alice: moving keys by hand".
+const (
+	// PausedAnnotation stops every change the operator makes, the status is still reported
+	PausedAnnotation = "middle.alauda.cn/paused"
+	// MaintenanceAnnotation keeps the Kubernetes objects reconciled but stops the operator from healing
+	// redis and the sentinels: no replica is re-pointed, no sentinel reset or monitored again
+	MaintenanceAnnotation = "middle.alauda.cn/maintenance"
+)
+
+// SwitchoverAnnotation asks the operator to move the master of a RedisFailover. Its value is
+// "<request-id>" to move it onto any replica or "<request-id>:<pod>" onto the given redis pod, the
+// request is run once per ID and reported in status.switchover.
+const SwitchoverAnnotation = "middle.alauda.cn/switchover"
+
+// GetSwitchoverRequest returns the ID and target pod of the switchover annotated on the object
+func GetSwitchoverRequest(obj metav1.Object) (string, string, bool) {
+	value, ok := obj.GetAnnotations()[SwitchoverAnnotation]
+	if !ok || value == "" {
+		return "", "", false
+	}
+	id, target := value, ""
+	if i := strings.Index(value, ":"); i >= 0 {
+		id, target = value[:i], value[i+1:]
+	}
+	return id, target, true
 }
 
-func (rp *RedisProxyStatus) DescConditionsByTime() {
-	sort.Slice(rp.Conditions, func(i, j int) bool {
-		return rp.Conditions[i].LastUpdateAt.After(rp.Conditions[j].LastUpdateAt.Time)
-	})
+// PauseMode is how far the operator is held off an instance
+type PauseMode string
+
+const (
+	PauseModeNone        PauseMode = ""
+	PauseModePaused      PauseMode = "Paused"
+	PauseModeMaintenance PauseMode = "Maintenance"
+)
+
+// GetPauseMode returns the pause mode annotated on the object and who set it, paused wins over maintenance
+func GetPauseMode(obj metav1.Object) (PauseMode, string) {
+	annotations := obj.GetAnnotations()
+	if by, ok := annotations[PausedAnnotation]; ok {
+		return PauseModePaused, by
+	}
+	if by, ok := annotations[MaintenanceAnnotation]; ok {
+		return PauseModeMaintenance, by
+	}
+	return PauseModeNone, ""
+}
+
+const (
+	reasonCreating    = "Creating"
+	reasonWaitingPods = "WaitingPods"
+	reasonScalingUp   = "ScalingUp"
+	reasonScalingDown = "ScalingDown"
+	reasonUpgrading   = "Upgrading"
+	reasonUpdating    = "Updating"
+	reasonTerminating = "Terminating"
+	reasonIdle        = "Idle"
+	reasonHealthy     = "Healthy"
+	reasonFailed      = "Failed"
+)
+
+var conditionTypes = map[string]bool{
+	ConditionReady:       true,
+	ConditionAvailable:   true,
+	ConditionProgressing: true,
+	ConditionDegraded:    true,
+	ConditionRestored:    true,
+	ConditionPaused:      true,
+}
+
+// conditionReasonRE is the format metav1.Condition requires of reasons
+var conditionReasonRE = regexp.MustCompile(`^[A-Za-z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$`)
+
+// migrateConditions drops the conditions written before the status moved to metav1.Condition: their
+// types are not the ones above, their reasons may hold spaces and they lack a transition time, so the
+// API server would reject every status update carrying them
+func migrateConditions(conditions []metav1.Condition) []metav1.Condition {
+	migrated := conditions[:0]
+	for _, c := range conditions {
+		if conditionTypes[c.Type] && conditionReasonRE.MatchString(c.Reason) && !c.LastTransitionTime.IsZero() {
+			migrated = append(migrated, c)
+		}
+	}
+	return migrated
+}
+
+// MigrateConditions drops the conditions written by earlier versions of the operator
+func (rf *RedisFailoverStatus) MigrateConditions() {
+	rf.Conditions = migrateConditions(rf.Conditions)
+}
+
+// MigrateConditions drops the conditions written by earlier versions of the operator
+func (rp *RedisProxyStatus) MigrateConditions() {
+	rp.Conditions = migrateConditions(rp.Conditions)
 }
 
 func (rf *RedisFailoverStatus) SetScalingUpCondition(message string) {
-	c := newRedisFailoverCondition(RedisFailoverConditionScaling, corev1.ConditionTrue, "Scaling up", message)
-	rf.setRedisFailoverCondition(*c)
+	rf.setCondition(ConditionProgressing, metav1.ConditionTrue, reasonScalingUp, message)
+	rf.setPendingPhase()
 }
 
 func (rf *RedisFailoverStatus) SetCreateCondition(message string) {
-	c := newRedisFailoverCondition(RedisFailoverConditionCreating, corev1.ConditionTrue, "Creating", message)
-	rf.setRedisFailoverCondition(*c)
+	rf.setCondition(ConditionProgressing, metav1.ConditionTrue, reasonCreating, message)
+	rf.setPendingPhase()
 }
 
-func (rf *RedisFailoverStatus) SetScalingDownCondition(message string) {
-	c := newRedisFailoverCondition(RedisFailoverConditionScaling, corev1.ConditionTrue, "Scaling down", message)
-	rf.setRedisFailoverCondition(*c)
+// SetScalingDownCondition reports the step the removal of redis pods is at
+func (rf *RedisFailoverStatus) SetScalingDownCondition(step string, message string) {
+	rf.setCondition(ConditionProgressing, metav1.ConditionTrue, reasonScalingDown, fmt.Sprintf("%s: %s", step, message))
+	rf.setPendingPhase()
 }
 
+// SetScaledDownCondition records the end of the removal of redis pods
+func (rf *RedisFailoverStatus) SetScaledDownCondition(message string) {
+	rf.setCondition(ConditionProgressing, metav1.ConditionFalse, reasonIdle, message)
+}
+
+// IsScalingDown reports whether redis pods are being removed
+func (rf *RedisFailoverStatus) IsScalingDown() bool {
+	return rf.isProgressing(reasonScalingDown)
+}
+
+// IsSwitchingOver reports whether a switchover request is pending or running
+func (rf *RedisFailoverStatus) IsSwitchingOver() bool {
+	return rf.Switchover != nil && (rf.Switchover.Phase == RedisSwitchoverPhasePending || rf.Switchover.Phase == RedisSwitchoverPhaseRunning)
+}
+
+// SetWaitingPodReadyCondition reports the redis pods are not all ready yet
 func (rf *RedisFailoverStatus) SetWaitingPodReadyCondition(message string) {
-	c := newRedisFailoverCondition(RedisFailoverConditionScaling, corev1.ConditionTrue, "WaitingPod", message)
-	rf.setRedisFailoverCondition(*c)
+	rf.setCondition(ConditionReady, metav1.ConditionFalse, reasonWaitingPods, message)
+	rf.setPendingPhase()
+}
 
+// IsWaitingPodReady reports whether the last check stopped waiting for the redis pods
+func (rf *RedisFailoverStatus) IsWaitingPodReady() bool {
+	c := meta.FindStatusCondition(rf.Conditions, ConditionReady)
+	return c != nil && c.Status == metav1.ConditionFalse && c.Reason == reasonWaitingPods
 }
 
-func (rf *RedisFailoverStatus) IsLastConditionWaitingPodReady() bool {
-	if len(rf.Conditions) > 0 {
-		rf.DescConditionsByTime()
+// SetUpgradingCondition reports the step the rolling upgrade of the redis pods is at
+func (rf *RedisFailoverStatus) SetUpgradingCondition(step string, message string) {
+	rf.setCondition(ConditionProgressing, metav1.ConditionTrue, reasonUpgrading, fmt.Sprintf("%s: %s", step, message))
+	rf.setPendingPhase()
+}
 
-		codition := rf.Conditions[0]
-		return codition.Reason == "WaitingPod"
-	}
-	return false
+// SetUpgradedCondition records the end of the rolling upgrade of the redis pods
+func (rf *RedisFailoverStatus) SetUpgradedCondition(message string) {
+	rf.setCondition(ConditionProgressing, metav1.ConditionFalse, reasonIdle, message)
 }
 
-func (rp *RedisProxyStatus) IsLastConditionUpgrading() bool {
-	if len(rp.Conditions) > 0 {
-		rp.DescConditionsByTime()
+// IsUpgrading reports whether a rolling upgrade of the redis pods is in progress
+func (rf *RedisFailoverStatus) IsUpgrading() bool {
+	return rf.isProgressing(reasonUpgrading)
+}
 
-		codition := rp.Conditions[0]
-		return codition.Reason == "RedisProxy Upgrading"
+func (rf *RedisFailoverStatus) SetUpdatingCondition(message string) {
+	rf.setCondition(ConditionProgressing, metav1.ConditionTrue, reasonUpdating, message)
+}
+
+// SetReadyCondition records a check that passed. The instance stays Progressing until an upgrade or
+// a scale-down is over.
+func (rf *RedisFailoverStatus) SetReadyCondition(message string) {
+	rf.setCondition(ConditionReady, metav1.ConditionTrue, reasonHealthy, message)
+	rf.setCondition(ConditionDegraded, metav1.ConditionFalse, reasonHealthy, message)
+	if rf.IsUpgrading() || rf.IsScalingDown() {
+		rf.Phase = RedisFailoverPhasePending
+		return
 	}
-	return false
+	rf.setCondition(ConditionProgressing, metav1.ConditionFalse, reasonIdle, message)
+	rf.Phase = RedisFailoverPhaseReady
 }
 
+func (rf *RedisFailoverStatus) SetFailedCondition(message string) {
+	rf.setCondition(ConditionReady, metav1.ConditionFalse, reasonFailed, message)
+	rf.setCondition(ConditionDegraded, metav1.ConditionTrue, reasonFailed, message)
+	if rf.Phase != RedisFailoverPhaseTerminating {
+		rf.Phase = RedisFailoverPhaseFailed
+	}
+}
 
+// SetAvailableCondition reports the master serving the instance
+func (rf *RedisFailoverStatus) SetAvailableCondition(message string) {
+	rf.setCondition(ConditionAvailable, metav1.ConditionTrue, "MasterAvailable", message)
+}
 
-func (rf *RedisFailoverStatus) SetUpgradingCondition(message string) {
-	c := newRedisFailoverCondition(RedisFailoverConditionUpgrading, corev1.ConditionTrue,
-		"RedisFailover upgrading", message)
-	rf.setRedisFailoverCondition(*c)
+// SetUnavailableCondition reports no single master could be found
+func (rf *RedisFailoverStatus) SetUnavailableCondition(message string) {
+	rf.setCondition(ConditionAvailable, metav1.ConditionFalse, "NoMaster", message)
 }
 
-func (rp *RedisProxyStatus) SetUpgradingCondition(message string) {
-	c := newRedisFailoverCondition(RedisFailoverConditionUpgrading, corev1.ConditionTrue,
-		"RedisProxy Upgrading", message)
-	rp.setRedisProxyCondition(*c)
+// SetRestoredCondition records the restore of Restore.BackupName as done
+func (rf *RedisFailoverStatus) SetRestoredCondition(message string) {
+	rf.setCondition(ConditionRestored, metav1.ConditionTrue, "Restored", message)
 }
 
+// SetRestoreSkippedCondition records that Restore.BackupName was set on an instance already running,
+// the restore only seeds new instances and is never attempted on it
+func (rf *RedisFailoverStatus) SetRestoreSkippedCondition(message string) {
+	rf.setCondition(ConditionRestored, metav1.ConditionFalse, "RestoreSkipped", message)
+}
 
-func (rf *RedisFailoverStatus) SetUpdatingCondition(message string) {
-	c := newRedisFailoverCondition(RedisFailoverConditionUpdating, corev1.ConditionTrue,
-		"RedisFailover updating", message)
-	rf.setRedisFailoverCondition(*c)
+// IsRestoreDone reports whether the restore has been handled, either restored or skipped
+func (rf *RedisFailoverStatus) IsRestoreDone() bool {
+	return meta.FindStatusCondition(rf.Conditions, ConditionRestored) != nil
 }
 
-func (rf *RedisFailoverStatus) SetReadyCondition(message string) {
-	c := newRedisFailoverCondition(RedisFailoverConditionHealthy, corev1.ConditionTrue, "RedisFailover available", message)
-	rf.setRedisFailoverCondition(*c)
+// SetTerminatingCondition reports the teardown step the deletion of the instance is at
+func (rf *RedisFailoverStatus) SetTerminatingCondition(step string, message string) {
+	rf.setCondition(ConditionReady, metav1.ConditionFalse, reasonTerminating, message)
+	rf.setCondition(ConditionProgressing, metav1.ConditionTrue, reasonTerminating, fmt.Sprintf("%s: %s", step, message))
+	rf.Phase = RedisFailoverPhaseTerminating
 }
 
-func (rp *RedisProxyStatus) SetReadyCondition(message string) {
-	c := newRedisFailoverCondition(RedisFailoverConditionHealthy, corev1.ConditionTrue, "Redis Ready", message)
-	rp.setRedisProxyCondition(*c)
+// SetPausedCondition reports the pause mode of the instance, once resumed the condition turns False
+func (rf *RedisFailoverStatus) SetPausedCondition(mode PauseMode, by string) {
+	setPausedCondition(&rf.Conditions, rf.ObservedGeneration, mode, by)
 }
 
-func (rf *RedisFailoverStatus) SetFailedCondition(message string) {
-	c := newRedisFailoverCondition(RedisFailoverConditionFailed, corev1.ConditionTrue,
-		"RedisFailover failed", message)
-	rf.setRedisFailoverCondition(*c)
+func (rf *RedisFailoverStatus) ClearCondition(t string) {
+	meta.RemoveStatusCondition(&rf.Conditions, t)
 }
 
-func (rf *RedisFailoverStatus) ClearCondition(t ConditionType) {
-	pos, _ := getRedisFailoverCondition(rf, t)
-	if pos == -1 {
-		return
+// setPendingPhase moves the instance to Pending, or keeps it Creating until it is first ready
+func (rf *RedisFailoverStatus) setPendingPhase() {
+	switch rf.Phase {
+	case "", RedisFailoverPhaseCreating:
+		rf.Phase = RedisFailoverPhaseCreating
+	case RedisFailoverPhaseTerminating:
+	default:
+		rf.Phase = RedisFailoverPhasePending
 	}
-	rf.Conditions = append(rf.Conditions[:pos], rf.Conditions[pos+1:]...)
 }
 
-func (rf *RedisFailoverStatus) setRedisFailoverCondition(c Condition) {
-	pos, cp := getRedisFailoverCondition(rf, c.Type)
-	if cp != nil &&
-		cp.Status == c.Status && cp.Reason == c.Reason && cp.Message == c.Message {
-		now := time.Now()
-		nowString := now.Format(time.RFC3339)
-		rf.Conditions[pos].LastUpdateAt = metav1.Time{Time: now}
-		rf.Conditions[pos].LastUpdateTime = nowString
-		return
-	}
+func (rf *RedisFailoverStatus) isProgressing(reason string) bool {
+	c := meta.FindStatusCondition(rf.Conditions, ConditionProgressing)
+	return c != nil && c.Status == metav1.ConditionTrue && c.Reason == reason
+}
 
-	if cp != nil {
-		rf.Conditions[pos] = c
-	} else {
-		rf.Conditions = append(rf.Conditions, c)
-	}
+func (rf *RedisFailoverStatus) setCondition(t string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&rf.Conditions, metav1.Condition{
+		Type:               t,
+		Status:             status,
+		ObservedGeneration: rf.ObservedGeneration,
+		Reason:             reason,
+		Message:            message,
+	})
 }
 
-func (rp *RedisProxyStatus) setRedisProxyCondition(c Condition) {
-	pos, cp := getRedisProxyCondition(rp, c.Type)
-	if cp != nil &&
-		cp.Status == c.Status && cp.Reason == c.Reason && cp.Message == c.Message {
-		now := time.Now()
-		nowString := now.Format(time.RFC3339)
-		rp.Conditions[pos].LastUpdateAt = metav1.Time{Time: now}
-		rp.Conditions[pos].LastUpdateTime = nowString
-		return
-	}
+func (rp *RedisProxyStatus) SetUpgradingCondition(message string) {
+	rp.setCondition(ConditionProgressing, metav1.ConditionTrue, reasonUpgrading, message)
+}
 
-	if cp != nil {
-		rp.Conditions[pos] = c
-	} else {
-		rp.Conditions = append(rp.Conditions, c)
-	}
+// IsUpgrading reports whether the proxies wait to be restarted on a new configuration
+func (rp *RedisProxyStatus) IsUpgrading() bool {
+	c := meta.FindStatusCondition(rp.Conditions, ConditionProgressing)
+	return c != nil && c.Status == metav1.ConditionTrue && c.Reason == reasonUpgrading
 }
 
+func (rp *RedisProxyStatus) SetReadyCondition(message string) {
+	rp.setCondition(ConditionReady, metav1.ConditionTrue, reasonHealthy, message)
+	rp.setCondition(ConditionProgressing, metav1.ConditionFalse, reasonIdle, message)
+}
 
-func getRedisFailoverCondition(status *RedisFailoverStatus, t ConditionType) (int, *Condition) {
-	for i, c := range status.Conditions {
-		if t == c.Type {
-			return i, &c
-		}
-	}
-	return -1, nil
+// SetPausedCondition reports the pause mode of the proxy, once resumed the condition turns False
+func (rp *RedisProxyStatus) SetPausedCondition(mode PauseMode, by string) {
+	setPausedCondition(&rp.Conditions, rp.ObservedGeneration, mode, by)
 }
 
-func getRedisProxyCondition(status *RedisProxyStatus, t ConditionType) (int, *Condition) {
-	for i, c := range status.Conditions {
-		if t == c.Type {
-			return i, &c
+func setPausedCondition(conditions *[]metav1.Condition, generation int64, mode PauseMode, by string) {
+	if mode == PauseModeNone {
+		if meta.IsStatusConditionTrue(*conditions, ConditionPaused) {
+			meta.SetStatusCondition(conditions, metav1.Condition{
+				Type:               ConditionPaused,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: generation,
+				Reason:             "Resumed",
+				Message:            "the operator manages the instance again",
+			})
 		}
+		return
 	}
-	return -1, nil
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               ConditionPaused,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             string(mode),
+		Message:            fmt.Sprintf("%s by %q", strings.ToLower(string(mode)), by),
+	})
 }
 
-
-func newRedisFailoverCondition(condType ConditionType, status corev1.ConditionStatus, reason, message string) *Condition {
-	now := time.Now()
-	nowString := now.Format(time.RFC3339)
-	return &Condition{
-		Type:               condType,
+func (rp *RedisProxyStatus) setCondition(t string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&rp.Conditions, metav1.Condition{
+		Type:               t,
 		Status:             status,
-		LastUpdateTime:     nowString,
-		LastUpdateAt:       metav1.Time{Time: now},
-		LastTransitionTime: nowString,
+		ObservedGeneration: rp.ObservedGeneration,
 		Reason:             reason,
 		Message:            message,
-	}
+	})
 }
@@ -0,0 +1,78 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMigrateConditions(t *testing.T) {
+	now := metav1.Now()
+	ready := metav1.Condition{Type: ConditionReady, Status: metav1.ConditionTrue, Reason: "Healthy", LastTransitionTime: now}
+	paused := metav1.Condition{Type: ConditionPaused, Status: metav1.ConditionFalse, Reason: "Resumed", LastTransitionTime: now}
+	tests := []struct {
+		name       string
+		conditions []metav1.Condition
+		want       []metav1.Condition
+	}{
+		{name: "none", conditions: nil, want: nil},
+		{name: "current conditions kept", conditions: []metav1.Condition{ready, paused}, want: []metav1.Condition{ready, paused}},
+		{
+			name:       "type of an earlier version",
+			conditions: []metav1.Condition{{Type: "Healthy", Status: metav1.ConditionTrue, Reason: "Healthy", LastTransitionTime: now}, ready},
+			want:       []metav1.Condition{ready},
+		},
+		{
+			name:       "reason with spaces",
+			conditions: []metav1.Condition{ready, {Type: ConditionDegraded, Status: metav1.ConditionTrue, Reason: "redis is down", LastTransitionTime: now}},
+			want:       []metav1.Condition{ready},
+		},
+		{
+			name:       "no transition time",
+			conditions: []metav1.Condition{{Type: ConditionProgressing, Status: metav1.ConditionTrue, Reason: "Creating"}, paused},
+			want:       []metav1.Condition{paused},
+		},
+		{
+			name:       "all dropped",
+			conditions: []metav1.Condition{{Type: "Creating", Reason: "Creating"}},
+			want:       []metav1.Condition{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := &RedisFailoverStatus{Conditions: tt.conditions}
+			status.MigrateConditions()
+			if !reflect.DeepEqual(status.Conditions, tt.want) {
+				t.Errorf("MigrateConditions() = %v, want %v", status.Conditions, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSwitchoverRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantID      string
+		wantTarget  string
+		wantOK      bool
+	}{
+		{name: "not annotated"},
+		{name: "empty", annotations: map[string]string{SwitchoverAnnotation: ""}},
+		{name: "any replica", annotations: map[string]string{SwitchoverAnnotation: "req-1"}, wantID: "req-1", wantOK: true},
+		{name: "target pod", annotations: map[string]string{SwitchoverAnnotation: "req-2:redis-1"}, wantID: "req-2", wantTarget: "redis-1", wantOK: true},
+		{name: "empty target", annotations: map[string]string{SwitchoverAnnotation: "req-3:"}, wantID: "req-3", wantOK: true},
+		{name: "target holding a colon", annotations: map[string]string{SwitchoverAnnotation: "req-4:a:b"}, wantID: "req-4", wantTarget: "a:b", wantOK: true},
+		{name: "other annotations", annotations: map[string]string{PausedAnnotation: "alice"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &RedisFailover{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			id, target, ok := GetSwitchoverRequest(rf)
+			if id != tt.wantID || target != tt.wantTarget || ok != tt.wantOK {
+				t.Errorf("GetSwitchoverRequest() = %q, %q, %v, want %q, %q, %v", id, target, ok, tt.wantID, tt.wantTarget, tt.wantOK)
+			}
+		})
+	}
+}
@@ -3,9 +3,14 @@ package v1alpha1
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -15,6 +20,15 @@ const (
 	defaultSentinelNumber  = 3
 	defaultRedisImage      = "redis:5.0.4-alpine"
 	defaultRedisProxyImage = "build-harbor.alauda.cn/middleware/redis-proxy:v3.7.0"
+	defaultBackupSize      = "1Gi"
+	defaultS3Image         = "amazon/aws-cli:2.4.6"
+	defaultS3Region        = "us-east-1"
+	defaultOpenSSLImage    = "alpine/openssl:latest"
+	defaultAppendFsync     = "everysec"
+
+	defaultSentinelDownAfterMilliseconds = 5000
+	defaultSentinelFailoverTimeout       = 3000
+	defaultSentinelParallelSyncs         = 2
 	// TODO : set default Slave
 	defaultSlavePriority = "1"
 )
@@ -47,6 +61,59 @@ func (r *RedisFailover) Validate() error {
 		r.Spec.Sentinel.Resources = defaultSentinelResource()
 	}
 
+	if err := r.Spec.Redis.Backup.Encryption.validate(); err != nil {
+		return err
+	}
+	if err := r.validateSentinel(); err != nil {
+		return err
+	}
+	if err := r.validateCommand(); err != nil {
+		return err
+	}
+	if err := r.validatePersistence(); err != nil {
+		return err
+	}
+	if err := r.validateCommandRenames(); err != nil {
+		return err
+	}
+	if err := r.validateExpose(); err != nil {
+		return err
+	}
+	if err := r.validateSource(); err != nil {
+		return err
+	}
+	if finalBackup := r.Spec.Redis.Backup.FinalBackup; finalBackup != nil {
+		if err := finalBackup.validate(); err != nil {
+			return fmt.Errorf("final backup: %v", err)
+		}
+	}
+	scheduleNames := map[string]bool{}
+	for i := range r.Spec.Redis.Backup.Schedule {
+		schedule := &r.Spec.Redis.Backup.Schedule[i]
+		if schedule.Name == "" {
+			return errors.New("backup schedule name is required")
+		}
+		// the name goes into the names and labels of the backups
+		if errs := validation.IsDNS1123Label(schedule.Name); len(errs) > 0 {
+			return fmt.Errorf("backup schedule name %s: %s", schedule.Name, strings.Join(errs, ", "))
+		}
+		// the name util.GetScheduledBackupName gives the backups
+		backupName := fmt.Sprintf("%s-%s-%d", r.Name, schedule.Name, time.Now().Unix()/60)
+		if errs := validation.IsDNS1123Subdomain(backupName); len(errs) > 0 {
+			return fmt.Errorf("backup schedule %s names its backups %s: %s", schedule.Name, backupName, strings.Join(errs, ", "))
+		}
+		if scheduleNames[schedule.Name] {
+			return fmt.Errorf("backup schedule %s is defined more than once", schedule.Name)
+		}
+		scheduleNames[schedule.Name] = true
+		if schedule.Keep < 1 {
+			return fmt.Errorf("backup schedule %s must keep at least one backup", schedule.Name)
+		}
+		if err := schedule.Storage.validate(); err != nil {
+			return fmt.Errorf("backup schedule %s: %v", schedule.Name, err)
+		}
+	}
+
 	// if r.Spec.Redis.ConfigConfigMap=="" {
 	// 	r.Spec.Redis.ConfigConfigMap = make(map[string]string)
 	// }
@@ -59,6 +126,192 @@ func (r *RedisFailover) Validate() error {
 	return nil
 }
 
+// masterNameRE keeps the master name usable as is in sentinel.conf, the scripts and SENTINEL RESET patterns
+var masterNameRE = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// sentinelConfigs are the sentinel settings of the master owned by the fields of SentinelSettings
+var sentinelConfigs = []string{"quorum", "down-after-milliseconds", "failover-timeout", "parallel-syncs", "notification-script", "client-reconfig-script"}
+
+func (r *RedisFailover) validateSentinel() error {
+	sentinel := &r.Spec.Sentinel
+	for _, config := range sentinel.CustomConfig {
+		param := strings.Fields(config)
+		if len(param) < 2 {
+			return fmt.Errorf("sentinel customConfig '%s' malformed", config)
+		}
+		for _, owned := range sentinelConfigs {
+			if strings.EqualFold(param[0], owned) {
+				return fmt.Errorf("sentinel customConfig %s is set by its sentinel field", param[0])
+			}
+		}
+	}
+	if sentinel.MasterName != "" && !masterNameRE.MatchString(sentinel.MasterName) {
+		return fmt.Errorf("sentinel masterName %s can only hold letters, digits, '.', '_' and '-'", sentinel.MasterName)
+	}
+	if sentinel.Shared != "" {
+		if sentinel.Shared == r.Name {
+			return errors.New("sentinel shared can't be the instance itself")
+		}
+		if r.Spec.Expose != nil && len(r.Spec.Expose.SentinelNodePorts) > 0 {
+			return errors.New("expose sentinelNodePorts can't be set when the sentinels are shared")
+		}
+	}
+	// the shared sentinels are counted on the RedisFailover running them
+	if sentinel.Quorum < 0 || (sentinel.Shared == "" && sentinel.Quorum > sentinel.Replicas) {
+		return fmt.Errorf("sentinel quorum must be between 1 and the %d sentinels", sentinel.Replicas)
+	}
+	if sentinel.DownAfterMilliseconds < 0 || sentinel.FailoverTimeout < 0 || sentinel.ParallelSyncs < 0 {
+		return errors.New("sentinel downAfterMilliseconds, failoverTimeout and parallelSyncs can't be negative")
+	}
+	if sentinel.DownAfterMilliseconds == 0 {
+		sentinel.DownAfterMilliseconds = defaultSentinelDownAfterMilliseconds
+	}
+	if sentinel.FailoverTimeout == 0 {
+		sentinel.FailoverTimeout = defaultSentinelFailoverTimeout
+	}
+	if sentinel.ParallelSyncs == 0 {
+		sentinel.ParallelSyncs = defaultSentinelParallelSyncs
+	}
+	return nil
+}
+
+// validateCommand rejects the settings that only reach redis through the redis.conf a custom Command may not load
+func (r *RedisFailover) validateCommand() error {
+	redis := r.Spec.Redis
+	if len(redis.Command) == 0 {
+		return nil
+	}
+	if redis.ConfigConfigMap != "" {
+		return errors.New("command can't be used with configConfigMap, which is only read from redis.conf")
+	}
+	if len(redis.CustomCommandRenames) > 0 {
+		return errors.New("command can't be used with customCommandRenames, which are only read from redis.conf")
+	}
+	return nil
+}
+
+// persistenceConfigs are the settings of redis.conf owned by Redis.Persistence
+var persistenceConfigs = []string{"save", "appendonly", "appendfsync"}
+
+func (r *RedisFailover) validatePersistence() error {
+	for key := range r.Spec.Redis.CustomConfig {
+		for _, config := range persistenceConfigs {
+			if strings.EqualFold(key, config) {
+				return fmt.Errorf("customConfig %s is set by persistence", key)
+			}
+		}
+	}
+
+	persistence := &r.Spec.Redis.Persistence
+	if persistence.Mode == "" {
+		persistence.Mode = RedisPersistenceModeRDB
+	}
+	mode := persistence.Mode
+	rdb := mode == RedisPersistenceModeRDB || mode == RedisPersistenceModeBoth
+	aof := mode == RedisPersistenceModeAOF || mode == RedisPersistenceModeBoth
+	switch {
+	case len(persistence.Save) > 0 && !rdb:
+		return fmt.Errorf("persistence save points don't apply to mode %s", mode)
+	case persistence.AppendFsync != "" && !aof:
+		return fmt.Errorf("persistence appendFsync doesn't apply to mode %s", mode)
+	}
+	for _, point := range persistence.Save {
+		if point.Seconds < 1 || point.Changes < 1 {
+			return errors.New("persistence save points need positive seconds and changes")
+		}
+	}
+	if rdb && len(persistence.Save) == 0 {
+		persistence.Save = []RedisSavePoint{{Seconds: 900, Changes: 1}, {Seconds: 300, Changes: 10}}
+	}
+	if aof && persistence.AppendFsync == "" {
+		persistence.AppendFsync = defaultAppendFsync
+	}
+
+	// redis ignores dump.rdb when appendonly is on, a restored instance would start empty. Once restored,
+	// the mode is applied live and the AOF is rewritten from the loaded data.
+	if aof && (r.Spec.Redis.Restore.BackupName != "" || r.Spec.Source != nil) && !r.Status.IsRestoreDone() {
+		return fmt.Errorf("persistence mode %s can't be used before the restore is done", mode)
+	}
+	if mode == RedisPersistenceModeNone {
+		// a restarted cache would load the RDB a backup or a shutdown left on the volume
+		if r.Spec.Redis.Storage.PersistentVolumeClaim != nil {
+			return errors.New("persistence mode none can't be used with a persistentVolumeClaim")
+		}
+		if len(r.Spec.Redis.Backup.Schedule) > 0 || r.Spec.Redis.Backup.FinalBackup != nil {
+			return errors.New("persistence mode none can't be used with backups")
+		}
+	}
+	return nil
+}
+
+// operatorCommands are the redis commands the operator, the probes and the shutdown script run,
+// they may be renamed but not disabled
+var operatorCommands = map[string]bool{
+	"config":    true,
+	"info":      true,
+	"ping":      true,
+	"save":      true,
+	"bgsave":    true,
+	"slaveof":   true,
+	"replicaof": true,
+}
+
+func (r *RedisFailover) validateCommandRenames() error {
+	renamed := map[string]bool{}
+	for _, rename := range r.Spec.Redis.CustomCommandRenames {
+		from := strings.ToLower(rename.From)
+		if from == "" {
+			return errors.New("customCommandRenames from is required")
+		}
+		if renamed[from] {
+			return fmt.Errorf("command %s is renamed more than once", rename.From)
+		}
+		renamed[from] = true
+		if rename.To == "" && operatorCommands[from] {
+			return fmt.Errorf("command %s is needed by the operator and can't be disabled", rename.From)
+		}
+		// without a volume to copy the RDB from, backups stream it with redis-cli --rdb, which sends SYNC
+		if (from == "sync" || from == "psync") && r.Spec.Redis.Storage.PersistentVolumeClaim == nil {
+			return fmt.Errorf("command %s is needed by backups without a persistentVolumeClaim and can't be renamed", rename.From)
+		}
+	}
+	return nil
+}
+
+func (r *RedisFailover) validateSource() error {
+	source := r.Spec.Source
+	if source == nil {
+		return nil
+	}
+	if r.Spec.Redis.Restore.BackupName != "" {
+		return errors.New("source and restore.backupName are mutually exclusive")
+	}
+	if (source.RedisFailover == "") == (source.Backup == "") {
+		return errors.New("source requires exactly one of redisFailover and backup")
+	}
+	ref := source.RedisFailover + source.Backup
+	namespace, name := r.Namespace, ref
+	if parts := strings.Split(ref, "/"); len(parts) > 1 {
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("source %s must be namespace/name or name", ref)
+		}
+		namespace, name = parts[0], parts[1]
+	}
+	if source.RedisFailover == "" {
+		return nil
+	}
+	if namespace == r.Namespace && name == r.Name {
+		return errors.New("source redisFailover can't be the instance itself")
+	}
+	if namespace != r.Namespace && source.Storage.S3 == nil {
+		return errors.New("cloning a redisFailover from another namespace requires source storage on s3")
+	}
+	if err := source.Encryption.validate(); err != nil {
+		return err
+	}
+	return source.Storage.validate()
+}
+
 func defaultSentinelResource() v1.ResourceRequirements {
 	return v1.ResourceRequirements{
 		Requests: v1.ResourceList{
@@ -95,6 +348,51 @@ func (rp *RedisProxy) Validate() error {
 	return nil
 }
 
+func (b *RedisBackup) Validate() error {
+	if b.Spec.RedisFailoverName == "" {
+		return errors.New("redisFailoverName is required")
+	}
+	if err := b.Spec.Encryption.validate(); err != nil {
+		return err
+	}
+	return b.Spec.Storage.validate()
+}
+
+func (e *RedisBackupEncryption) validate() error {
+	if e == nil {
+		return nil
+	}
+	if e.SecretName == "" || e.KeyID == "" {
+		return errors.New("encryption secretName and keyID are required")
+	}
+	if e.Image == "" {
+		e.Image = defaultOpenSSLImage
+	}
+	return nil
+}
+
+func (s *RedisBackupStorage) validate() error {
+	if s.Size.IsZero() {
+		s.Size = resource.MustParse(defaultBackupSize)
+	}
+	if s.S3 == nil {
+		return nil
+	}
+	if s.S3.Endpoint == "" || s.S3.Bucket == "" {
+		return errors.New("s3 endpoint and bucket are required")
+	}
+	if s.S3.CredentialsSecret == "" {
+		return errors.New("s3 credentialsSecret is required")
+	}
+	if s.S3.Region == "" {
+		s.S3.Region = defaultS3Region
+	}
+	if s.S3.Image == "" {
+		s.S3.Image = defaultS3Image
+	}
+	return nil
+}
+
 func defaultProxyResource() v1.ResourceRequirements {
 	return v1.ResourceRequirements{
 		Requests: v1.ResourceList{
@@ -107,3 +405,70 @@ func defaultProxyResource() v1.ResourceRequirements {
 		},
 	}
 }
+
+func (r *RedisFailover) validateExpose() error {
+	expose := r.Spec.Expose
+	if expose == nil {
+		return nil
+	}
+	switch expose.Type {
+	case "":
+		expose.Type = v1.ServiceTypeNodePort
+	case v1.ServiceTypeNodePort, v1.ServiceTypeLoadBalancer:
+	default:
+		return fmt.Errorf("expose type must be %s or %s", v1.ServiceTypeNodePort, v1.ServiceTypeLoadBalancer)
+	}
+	if len(expose.RedisNodePorts) != 0 && int32(len(expose.RedisNodePorts)) != r.Spec.Redis.Replicas {
+		return errors.New("expose redisNodePorts must list a port for every redis replica")
+	}
+	if len(expose.SentinelNodePorts) != 0 && int32(len(expose.SentinelNodePorts)) != r.Spec.Sentinel.Replicas {
+		return errors.New("expose sentinelNodePorts must list a port for every sentinel replica")
+	}
+	// shared sentinels announce the address of the RedisFailover running them
+	if r.Spec.Sentinel.Shared == "" {
+		if version := getImageVersion(r.Spec.Sentinel.Image); version != "" && !CanSentinelAnnounce(version) {
+			return fmt.Errorf("expose requires sentinels of redis 6.2 or later, sentinel image %s is redis %s", r.Spec.Sentinel.Image, version)
+		}
+	}
+	return nil
+}
+
+// CanSentinelAnnounce reports whether sentinels of the given redis_version support SENTINEL CONFIG SET
+func CanSentinelAnnounce(version string) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return major > 6 || major == 6 && minor >= 2
+}
+
+// getImageVersion returns the major.minor.patch redis version the tag of image names, like 5.0.4 for
+// redis:5.0.4-alpine. It is empty when the tag names no minor version, latest or 7-alpine for instance.
+func getImageVersion(image string) string {
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+	version := strings.SplitN(image[i+1:], "-", 2)[0]
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		return ""
+	}
+	for _, part := range parts {
+		if _, err := strconv.Atoi(part); err != nil {
+			return ""
+		}
+	}
+	return version
+}
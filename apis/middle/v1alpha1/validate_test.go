@@ -0,0 +1,343 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateCommandRenames(t *testing.T) {
+	tests := []struct {
+		name    string
+		renames []RedisCommandRename
+		pvc     bool
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "renamed", renames: []RedisCommandRename{{From: "flushall", To: "f1"}, {From: "CONFIG", To: "c1"}}},
+		{name: "disabled", renames: []RedisCommandRename{{From: "keys", To: ""}}},
+		{name: "from missing", renames: []RedisCommandRename{{To: "f1"}}, wantErr: true},
+		{name: "renamed twice, whatever the case", renames: []RedisCommandRename{{From: "keys", To: "k1"}, {From: "KEYS", To: "k2"}}, wantErr: true},
+		{name: "operator command renamed", renames: []RedisCommandRename{{From: "slaveof", To: "s1"}}},
+		{name: "operator command disabled", renames: []RedisCommandRename{{From: "Info", To: ""}}, wantErr: true},
+		{name: "sync renamed without a volume", renames: []RedisCommandRename{{From: "sync", To: "s1"}}, wantErr: true},
+		{name: "psync disabled without a volume", renames: []RedisCommandRename{{From: "PSYNC", To: ""}}, wantErr: true},
+		{name: "sync renamed with a volume", renames: []RedisCommandRename{{From: "sync", To: "s1"}}, pvc: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &RedisFailover{}
+			rf.Spec.Redis.CustomCommandRenames = tt.renames
+			if tt.pvc {
+				rf.Spec.Redis.Storage.PersistentVolumeClaim = &corev1.PersistentVolumeClaim{}
+			}
+			if err := rf.validateCommandRenames(); (err != nil) != tt.wantErr {
+				t.Errorf("validateCommandRenames() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePersistence(t *testing.T) {
+	defaultSave := []RedisSavePoint{{Seconds: 900, Changes: 1}, {Seconds: 300, Changes: 10}}
+	tests := []struct {
+		name         string
+		persistence  RedisPersistence
+		customConfig map[string]string
+		pvc          bool
+		backups      bool
+		restore      bool
+		restored     bool
+		want         RedisPersistence
+		wantErr      bool
+	}{
+		{
+			name: "defaults to rdb with the default save points",
+			want: RedisPersistence{Mode: RedisPersistenceModeRDB, Save: defaultSave},
+		},
+		{
+			name:        "rdb with save points",
+			persistence: RedisPersistence{Mode: RedisPersistenceModeRDB, Save: []RedisSavePoint{{Seconds: 60, Changes: 100}}},
+			want:        RedisPersistence{Mode: RedisPersistenceModeRDB, Save: []RedisSavePoint{{Seconds: 60, Changes: 100}}},
+		},
+		{
+			name:        "aof defaults to everysec",
+			persistence: RedisPersistence{Mode: RedisPersistenceModeAOF},
+			want:        RedisPersistence{Mode: RedisPersistenceModeAOF, AppendFsync: "everysec"},
+		},
+		{
+			name:        "both",
+			persistence: RedisPersistence{Mode: RedisPersistenceModeBoth, AppendFsync: "always"},
+			want:        RedisPersistence{Mode: RedisPersistenceModeBoth, Save: defaultSave, AppendFsync: "always"},
+		},
+		{
+			name:        "none",
+			persistence: RedisPersistence{Mode: RedisPersistenceModeNone},
+			want:        RedisPersistence{Mode: RedisPersistenceModeNone},
+		},
+		{
+			name:        "save points of aof",
+			persistence: RedisPersistence{Mode: RedisPersistenceModeAOF, Save: defaultSave},
+			wantErr:     true,
+		},
+		{
+			name:        "appendFsync of rdb",
+			persistence: RedisPersistence{Mode: RedisPersistenceModeRDB, AppendFsync: "always"},
+			wantErr:     true,
+		},
+		{
+			name:        "save point without changes",
+			persistence: RedisPersistence{Save: []RedisSavePoint{{Seconds: 60}}},
+			wantErr:     true,
+		},
+		{
+			name:         "customConfig owned by persistence",
+			customConfig: map[string]string{"AppendOnly": "yes"},
+			wantErr:      true,
+		},
+		{
+			name:        "none on a persistentVolumeClaim",
+			persistence: RedisPersistence{Mode: RedisPersistenceModeNone},
+			pvc:         true,
+			wantErr:     true,
+		},
+		{
+			name:        "none with backups",
+			persistence: RedisPersistence{Mode: RedisPersistenceModeNone},
+			backups:     true,
+			wantErr:     true,
+		},
+		{
+			name:        "aof before the restore",
+			persistence: RedisPersistence{Mode: RedisPersistenceModeAOF},
+			restore:     true,
+			wantErr:     true,
+		},
+		{
+			name:        "both before the restore",
+			persistence: RedisPersistence{Mode: RedisPersistenceModeBoth},
+			restore:     true,
+			wantErr:     true,
+		},
+		{
+			name:        "rdb before the restore",
+			persistence: RedisPersistence{Mode: RedisPersistenceModeRDB},
+			restore:     true,
+			want:        RedisPersistence{Mode: RedisPersistenceModeRDB, Save: defaultSave},
+		},
+		{
+			name:        "aof once restored",
+			persistence: RedisPersistence{Mode: RedisPersistenceModeAOF},
+			restore:     true,
+			restored:    true,
+			want:        RedisPersistence{Mode: RedisPersistenceModeAOF, AppendFsync: "everysec"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &RedisFailover{}
+			if tt.restore {
+				rf.Spec.Source = &RedisFailoverSource{Backup: "origin-backup"}
+			}
+			if tt.restored {
+				rf.Status.SetRestoredCondition("restored")
+			}
+			rf.Spec.Redis.Persistence = tt.persistence
+			rf.Spec.Redis.CustomConfig = tt.customConfig
+			if tt.pvc {
+				rf.Spec.Redis.Storage.PersistentVolumeClaim = &corev1.PersistentVolumeClaim{}
+			}
+			if tt.backups {
+				rf.Spec.Redis.Backup.Schedule = []Schedule{{Name: "daily"}}
+			}
+			err := rf.validatePersistence()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validatePersistence() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(rf.Spec.Redis.Persistence, tt.want) {
+				t.Errorf("validatePersistence() persistence = %+v, want %+v", rf.Spec.Redis.Persistence, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		redis   RedisSettings
+		wantErr bool
+	}{
+		{name: "no command", redis: RedisSettings{ConfigConfigMap: "conf"}},
+		{name: "command", redis: RedisSettings{Command: []string{"redis-server"}}},
+		{name: "command and configConfigMap", redis: RedisSettings{Command: []string{"redis-server"}, ConfigConfigMap: "conf"}, wantErr: true},
+		{
+			name:    "command and customCommandRenames",
+			redis:   RedisSettings{Command: []string{"redis-server"}, CustomCommandRenames: []RedisCommandRename{{From: "keys"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &RedisFailover{}
+			rf.Spec.Redis = tt.redis
+			if err := rf.validateCommand(); (err != nil) != tt.wantErr {
+				t.Errorf("validateCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  *RedisFailoverSource
+		restore string
+		wantErr bool
+	}{
+		{name: "no source"},
+		{name: "redisFailover", source: &RedisFailoverSource{RedisFailover: "origin"}},
+		{name: "backup", source: &RedisFailoverSource{Backup: "origin-backup"}},
+		{name: "neither", source: &RedisFailoverSource{}, wantErr: true},
+		{name: "both", source: &RedisFailoverSource{RedisFailover: "origin", Backup: "origin-backup"}, wantErr: true},
+		{name: "with restore", source: &RedisFailoverSource{Backup: "origin-backup"}, restore: "other-backup", wantErr: true},
+		{
+			name:   "redisFailover in another namespace",
+			source: &RedisFailoverSource{RedisFailover: "other/origin", Storage: RedisBackupStorage{S3: &RedisBackupS3Storage{Endpoint: "http://minio:9000", Bucket: "backups", CredentialsSecret: "s3"}}},
+		},
+		{name: "redisFailover in another namespace without s3", source: &RedisFailoverSource{RedisFailover: "other/origin"}, wantErr: true},
+		{name: "redisFailover in its own namespace", source: &RedisFailoverSource{RedisFailover: "default/origin"}},
+		{name: "backup in another namespace", source: &RedisFailoverSource{Backup: "other/origin-backup"}},
+		{name: "namespace missing", source: &RedisFailoverSource{Backup: "/origin-backup"}, wantErr: true},
+		{name: "name missing", source: &RedisFailoverSource{RedisFailover: "other/"}, wantErr: true},
+		{name: "too many parts", source: &RedisFailoverSource{Backup: "other/origin/backup"}, wantErr: true},
+		{name: "the instance itself", source: &RedisFailoverSource{RedisFailover: "clone"}, wantErr: true},
+		{name: "the instance itself by namespace", source: &RedisFailoverSource{RedisFailover: "default/clone"}, wantErr: true},
+		{name: "same name in another namespace", source: &RedisFailoverSource{Backup: "other/clone"}},
+		{
+			name:    "redisFailover with an incomplete encryption",
+			source:  &RedisFailoverSource{RedisFailover: "origin", Encryption: &RedisBackupEncryption{SecretName: "keys"}},
+			wantErr: true,
+		},
+		{
+			name:    "redisFailover with an incomplete s3 storage",
+			source:  &RedisFailoverSource{RedisFailover: "origin", Storage: RedisBackupStorage{S3: &RedisBackupS3Storage{Bucket: "backups"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &RedisFailover{}
+			rf.Name, rf.Namespace = "clone", "default"
+			rf.Spec.Source = tt.source
+			rf.Spec.Redis.Restore.BackupName = tt.restore
+			if err := rf.validateSource(); (err != nil) != tt.wantErr {
+				t.Errorf("validateSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateBackupSchedules(t *testing.T) {
+	tests := []struct {
+		name      string
+		schedules []Schedule
+		wantErr   bool
+	}{
+		{name: "none"},
+		{name: "valid", schedules: []Schedule{{Name: "daily", Schedule: "0 0 * * *", Keep: 7}, {Name: "hourly-1", Keep: 1}}},
+		{name: "name missing", schedules: []Schedule{{Keep: 1}}, wantErr: true},
+		{name: "upper case name", schedules: []Schedule{{Name: "Daily", Keep: 1}}, wantErr: true},
+		{name: "name with a dot", schedules: []Schedule{{Name: "every.day", Keep: 1}}, wantErr: true},
+		{name: "name too long for a label", schedules: []Schedule{{Name: strings.Repeat("a", 64), Keep: 1}}, wantErr: true},
+		{name: "longest label name", schedules: []Schedule{{Name: strings.Repeat("a", 63), Keep: 1}}},
+		{name: "defined twice", schedules: []Schedule{{Name: "daily", Keep: 1}, {Name: "daily", Keep: 2}}, wantErr: true},
+		{name: "keeps nothing", schedules: []Schedule{{Name: "daily"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &RedisFailover{}
+			rf.Name = "redis"
+			rf.Spec.Redis.Backup.Schedule = tt.schedules
+			if err := rf.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCanSentinelAnnounce(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{version: "5.0.4", want: false},
+		{version: "6.0.16", want: false},
+		{version: "6.2.0", want: true},
+		{version: "6.2", want: true},
+		{version: "7.0.5", want: true},
+		{version: "10.0.0", want: true},
+		{version: "6", want: false},
+		{version: "", want: false},
+		{version: "unstable", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := CanSentinelAnnounce(tt.version); got != tt.want {
+				t.Errorf("CanSentinelAnnounce(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetImageVersion(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "redis:5.0.4-alpine", want: "5.0.4"},
+		{image: "redis:6.2", want: "6.2"},
+		{image: "registry:5000/library/redis:7.0.5", want: "7.0.5"},
+		{image: "registry:5000/library/redis", want: ""},
+		{image: "redis", want: ""},
+		{image: "redis:latest", want: ""},
+		{image: "redis:7-alpine", want: ""},
+		{image: "redis:6.x", want: ""},
+		{image: "redis@sha256:0123", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := getImageVersion(tt.image); got != tt.want {
+				t.Errorf("getImageVersion(%q) = %q, want %q", tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateExpose(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   string
+		shared  string
+		wantErr bool
+	}{
+		{name: "default image", wantErr: true},
+		{name: "sentinels too old", image: "redis:6.0.16", wantErr: true},
+		{name: "sentinels announcing", image: "redis:6.2.6-alpine"},
+		{name: "image without version", image: "redis:latest"},
+		{name: "shared sentinels", image: "redis:5.0.4", shared: "sentinels"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &RedisFailover{}
+			rf.Name = "redis"
+			rf.Spec.Expose = &RedisFailoverExpose{}
+			rf.Spec.Sentinel.Image = tt.image
+			rf.Spec.Sentinel.Shared = tt.shared
+			if err := rf.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
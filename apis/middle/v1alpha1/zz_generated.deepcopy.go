@@ -1,3 +1,4 @@
+//go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
 /*
@@ -22,6 +23,7 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -41,17 +43,16 @@ func (in *AuthSettings) DeepCopy() *AuthSettings {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Condition) DeepCopyInto(out *Condition) {
+func (in *ProxyInfo) DeepCopyInto(out *ProxyInfo) {
 	*out = *in
-	in.LastUpdateAt.DeepCopyInto(&out.LastUpdateAt)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
-func (in *Condition) DeepCopy() *Condition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyInfo.
+func (in *ProxyInfo) DeepCopy() *ProxyInfo {
 	if in == nil {
 		return nil
 	}
-	out := new(Condition)
+	out := new(ProxyInfo)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -61,8 +62,8 @@ func (in *RedisBackup) DeepCopyInto(out *RedisBackup) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisBackup.
@@ -83,6 +84,21 @@ func (in *RedisBackup) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisBackupEncryption) DeepCopyInto(out *RedisBackupEncryption) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisBackupEncryption.
+func (in *RedisBackupEncryption) DeepCopy() *RedisBackupEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisBackupEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisBackupList) DeepCopyInto(out *RedisBackupList) {
 	*out = *in
@@ -115,9 +131,29 @@ func (in *RedisBackupList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisBackupS3Storage) DeepCopyInto(out *RedisBackupS3Storage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisBackupS3Storage.
+func (in *RedisBackupS3Storage) DeepCopy() *RedisBackupS3Storage {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisBackupS3Storage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisBackupSetting) DeepCopyInto(out *RedisBackupSetting) {
 	*out = *in
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(RedisBackupEncryption)
+		**out = **in
+	}
 	if in.Schedule != nil {
 		in, out := &in.Schedule, &out.Schedule
 		*out = make([]Schedule, len(*in))
@@ -125,6 +161,11 @@ func (in *RedisBackupSetting) DeepCopyInto(out *RedisBackupSetting) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.FinalBackup != nil {
+		in, out := &in.FinalBackup, &out.FinalBackup
+		*out = new(RedisBackupStorage)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisBackupSetting.
@@ -140,6 +181,12 @@ func (in *RedisBackupSetting) DeepCopy() *RedisBackupSetting {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisBackupSpec) DeepCopyInto(out *RedisBackupSpec) {
 	*out = *in
+	in.Storage.DeepCopyInto(&out.Storage)
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(RedisBackupEncryption)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisBackupSpec.
@@ -155,6 +202,21 @@ func (in *RedisBackupSpec) DeepCopy() *RedisBackupSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisBackupStatus) DeepCopyInto(out *RedisBackupStatus) {
 	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Keyspace != nil {
+		in, out := &in.Keyspace, &out.Keyspace
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisBackupStatus.
@@ -171,6 +233,11 @@ func (in *RedisBackupStatus) DeepCopy() *RedisBackupStatus {
 func (in *RedisBackupStorage) DeepCopyInto(out *RedisBackupStorage) {
 	*out = *in
 	out.Size = in.Size.DeepCopy()
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(RedisBackupS3Storage)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisBackupStorage.
@@ -240,6 +307,38 @@ func (in *RedisFailover) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisFailoverExpose) DeepCopyInto(out *RedisFailoverExpose) {
+	*out = *in
+	if in.RedisNodePorts != nil {
+		in, out := &in.RedisNodePorts, &out.RedisNodePorts
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.SentinelNodePorts != nil {
+		in, out := &in.SentinelNodePorts, &out.SentinelNodePorts
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisFailoverExpose.
+func (in *RedisFailoverExpose) DeepCopy() *RedisFailoverExpose {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisFailoverExpose)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisFailoverList) DeepCopyInto(out *RedisFailoverList) {
 	*out = *in
@@ -272,6 +371,27 @@ func (in *RedisFailoverList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisFailoverSource) DeepCopyInto(out *RedisFailoverSource) {
+	*out = *in
+	in.Storage.DeepCopyInto(&out.Storage)
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(RedisBackupEncryption)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisFailoverSource.
+func (in *RedisFailoverSource) DeepCopy() *RedisFailoverSource {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisFailoverSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisFailoverSpec) DeepCopyInto(out *RedisFailoverSpec) {
 	*out = *in
@@ -283,6 +403,16 @@ func (in *RedisFailoverSpec) DeepCopyInto(out *RedisFailoverSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(RedisFailoverSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Expose != nil {
+		in, out := &in.Expose, &out.Expose
+		*out = new(RedisFailoverExpose)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisFailoverSpec.
@@ -300,13 +430,35 @@ func (in *RedisFailoverStatus) DeepCopyInto(out *RedisFailoverStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 	out.Instance = in.Instance
 	out.Master = in.Master
+	if in.Switchover != nil {
+		in, out := &in.Switchover, &out.Switchover
+		*out = new(RedisSwitchoverStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SentinelCommandRenames != nil {
+		in, out := &in.SentinelCommandRenames, &out.SentinelCommandRenames
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PendingFailover != nil {
+		in, out := &in.PendingFailover, &out.PendingFailover
+		*out = new(RedisPendingFailover)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExcludedReplicas != nil {
+		in, out := &in.ExcludedReplicas, &out.ExcludedReplicas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisFailoverStatus.
@@ -319,13 +471,49 @@ func (in *RedisFailoverStatus) DeepCopy() *RedisFailoverStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisPendingFailover) DeepCopyInto(out *RedisPendingFailover) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisPendingFailover.
+func (in *RedisPendingFailover) DeepCopy() *RedisPendingFailover {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisPendingFailover)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisPersistence) DeepCopyInto(out *RedisPersistence) {
+	*out = *in
+	if in.Save != nil {
+		in, out := &in.Save, &out.Save
+		*out = make([]RedisSavePoint, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisPersistence.
+func (in *RedisPersistence) DeepCopy() *RedisPersistence {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisPersistence)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisProxy) DeepCopyInto(out *RedisProxy) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisProxy.
@@ -381,6 +569,52 @@ func (in *RedisProxyList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisProxySpec) DeepCopyInto(out *RedisProxySpec) {
 	*out = *in
+	out.ProxyInfo = in.ProxyInfo
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(v1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	out.Auth = in.Auth
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PodAnnotations != nil {
+		in, out := &in.PodAnnotations, &out.PodAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ServiceAnnotations != nil {
+		in, out := &in.ServiceAnnotations, &out.ServiceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisProxySpec.
@@ -396,6 +630,13 @@ func (in *RedisProxySpec) DeepCopy() *RedisProxySpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisProxyStatus) DeepCopyInto(out *RedisProxyStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisProxyStatus.
@@ -423,6 +664,21 @@ func (in *RedisRestore) DeepCopy() *RedisRestore {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisSavePoint) DeepCopyInto(out *RedisSavePoint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisSavePoint.
+func (in *RedisSavePoint) DeepCopy() *RedisSavePoint {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisSavePoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisSettings) DeepCopyInto(out *RedisSettings) {
 	*out = *in
@@ -445,6 +701,7 @@ func (in *RedisSettings) DeepCopyInto(out *RedisSettings) {
 		copy(*out, *in)
 	}
 	in.Storage.DeepCopyInto(&out.Storage)
+	in.Persistence.DeepCopyInto(&out.Persistence)
 	out.Exporter = in.Exporter
 	if in.Affinity != nil {
 		in, out := &in.Affinity, &out.Affinity
@@ -590,6 +847,29 @@ func (in *RedisStorage) DeepCopy() *RedisStorage {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisSwitchoverStatus) DeepCopyInto(out *RedisSwitchoverStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisSwitchoverStatus.
+func (in *RedisSwitchoverStatus) DeepCopy() *RedisSwitchoverStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisSwitchoverStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Schedule) DeepCopyInto(out *Schedule) {
 	*out = *in
@@ -0,0 +1,37 @@
+package backupservice
+
+import (
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/client/k8s"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type RedisBackupClient interface {
+	EnsureBackupPersistentVolumeClaim(b *middlev1alpha1.RedisBackup, labels map[string]string, ownerRefs []metav1.OwnerReference) error
+	EnsureBackupJob(b *middlev1alpha1.RedisBackup, rf *middlev1alpha1.RedisFailover, source *corev1.Pod, labels map[string]string, ownerRefs []metav1.OwnerReference) error
+}
+
+type RedisBackupKubeClient struct {
+	K8SService   k8s.Services
+	Logger       logr.Logger
+	StatusWriter client.StatusWriter
+	Record       record.EventRecorder
+}
+
+func NewRedisBackupKubeClient(k8SService k8s.Services, log logr.Logger, status client.StatusWriter, record record.EventRecorder) *RedisBackupKubeClient {
+	return &RedisBackupKubeClient{K8SService: k8SService, Logger: log, StatusWriter: status, Record: record}
+}
+
+func (r RedisBackupKubeClient) EnsureBackupPersistentVolumeClaim(b *middlev1alpha1.RedisBackup, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
+	pvc := generateBackupPersistentVolumeClaim(b, labels, ownerRefs)
+	return r.K8SService.CreateIfNotExistsPersistentVolumeClaim(b.Namespace, pvc)
+}
+
+func (r RedisBackupKubeClient) EnsureBackupJob(b *middlev1alpha1.RedisBackup, rf *middlev1alpha1.RedisFailover, source *corev1.Pod, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
+	job := generateBackupJob(b, rf, source, labels, ownerRefs)
+	return r.K8SService.CreateIfNotExistsJob(b.Namespace, job)
+}
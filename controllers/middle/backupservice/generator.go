@@ -0,0 +1,252 @@
+package backupservice
+
+import (
+	"fmt"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	backupVolumeName        = "backup-data"
+	backupMountPath         = "/backup"
+	backupStagingVolumeName = "backup-staging"
+	backupStagingMountPath  = "/staging"
+	backupEncryptionKeyEnv  = "BACKUP_ENCRYPTION_KEY"
+	// backupMetadataFileName holds the metadata of the verified artifact next to it
+	backupMetadataFileName = "metadata"
+	redisDataVolumeName    = "redis-data"
+	redisDataMountPath     = "/data"
+	redisPasswordEnv       = "REDIS_PASSWORD"
+	backupBackoffLimit     = 1
+)
+
+func generateBackupPersistentVolumeClaim(b *middlev1alpha1.RedisBackup, labels map[string]string, ownerRefs []metav1.OwnerReference) *corev1.PersistentVolumeClaim {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            util2.GetBackupPersistentVolumeClaimName(b),
+			Namespace:       b.Namespace,
+			Labels:          labels,
+			OwnerReferences: ownerRefs,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: b.Spec.Storage.Size,
+				},
+			},
+		},
+	}
+	if b.Spec.Storage.StorageClassName != "" {
+		pvc.Spec.StorageClassName = &b.Spec.Storage.StorageClassName
+	}
+	return pvc
+}
+
+// generateBackupJob builds the Job copying the RDB of the source pod into the backup volume.
+// When the redis data sits on a PersistentVolumeClaim the Job runs on the node of the source
+// pod and copies the dump.rdb written by BGSAVE, otherwise it streams a snapshot with redis-cli --rdb.
+// The steps run in order as init containers, the last one as the main container: the RDB is taken
+// and verified, then encrypted when the backup has an encryption key, then uploaded when stored on S3.
+func generateBackupJob(b *middlev1alpha1.RedisBackup, rf *middlev1alpha1.RedisFailover, source *corev1.Pod, labels map[string]string, ownerRefs []metav1.OwnerReference) *batchv1.Job {
+	backoffLimit := int32(backupBackoffLimit)
+	image := b.Spec.Image
+	if image == "" {
+		image = rf.Spec.Redis.Image
+	}
+
+	// backups going to S3 are only staged in the pod before the upload
+	backupVolumeSource := corev1.VolumeSource{
+		EmptyDir: &corev1.EmptyDirVolumeSource{},
+	}
+	if b.Spec.Storage.S3 == nil {
+		backupVolumeSource = corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: util2.GetBackupPersistentVolumeClaimName(b),
+			},
+		}
+	}
+	volumes := []corev1.Volume{
+		{
+			Name:         backupVolumeName,
+			VolumeSource: backupVolumeSource,
+		},
+	}
+	volumeMounts := []corev1.VolumeMount{
+		{
+			Name:      backupVolumeName,
+			MountPath: backupMountPath,
+		},
+	}
+	// the plaintext RDB of an encrypted backup never reaches the backup volume
+	workDir := backupMountPath
+	if b.Spec.Encryption != nil {
+		workDir = backupStagingMountPath
+		volumes = append(volumes, corev1.Volume{
+			Name: backupStagingVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      backupStagingVolumeName,
+			MountPath: backupStagingMountPath,
+		})
+	}
+	rdb := fmt.Sprintf("%s/%s", workDir, util2.BackupFileName)
+	metadata := fmt.Sprintf("%s/%s", workDir, backupMetadataFileName)
+	artifact := fmt.Sprintf("%s/%s", backupMountPath, util2.GetBackupFileName(b))
+
+	backupMounts := volumeMounts
+	var copyCommand, nodeName string
+	if rf.Spec.Redis.Storage.PersistentVolumeClaim != nil {
+		nodeName = source.Spec.NodeName
+		volumes = append(volumes, corev1.Volume{
+			Name: redisDataVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: fmt.Sprintf("%s-%s", rf.Spec.Redis.Storage.PersistentVolumeClaim.Name, source.Name),
+					ReadOnly:  true,
+				},
+			},
+		})
+		backupMounts = append(backupMounts, corev1.VolumeMount{
+			Name:      redisDataVolumeName,
+			MountPath: redisDataMountPath,
+			ReadOnly:  true,
+		})
+		copyCommand = fmt.Sprintf("cp %s/%s %s", redisDataMountPath, util2.BackupFileName, rdb)
+	} else {
+		copyCommand = fmt.Sprintf("redis-cli -h %s -p 6379", source.Status.PodIP)
+		if rf.Spec.Auth.SecretPath != "" {
+			copyCommand = fmt.Sprintf(`%s -a "${%s}"`, copyCommand, redisPasswordEnv)
+		}
+		copyCommand = fmt.Sprintf("%s --rdb %s", copyCommand, rdb)
+	}
+	// the artifact is verified with redis-check-rdb, which checks its header and CRC64 trailer, and its
+	// metadata is handed back to the operator as key=value lines through the termination message
+	backupContent := fmt.Sprintf(`set -e
+%[1]s
+if ! redis-check-rdb %[2]s > /tmp/check-rdb.log 2>&1; then
+  tail -n 3 /tmp/check-rdb.log > /dev/termination-log
+  exit 1
+fi
+{
+  echo "size=$(wc -c < %[2]s | tr -d ' ')"
+  echo "rdbVersion=$(head -c 9 %[2]s | tail -c 4)"
+  sed -n "s/.*AUX FIELD redis-ver = '\(.*\)'.*/redisVersion=\1/p" /tmp/check-rdb.log
+  sed -n 's/^\[info\] \([0-9]*\) keys read.*/keys=\1/p' /tmp/check-rdb.log
+} > %[3]s`, copyCommand, rdb, metadata)
+
+	resources := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("256Mi"),
+		},
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+			corev1.ResourceMemory: resource.MustParse("64Mi"),
+		},
+	}
+	steps := []corev1.Container{
+		{
+			Name:            "backup",
+			Image:           image,
+			ImagePullPolicy: util2.PullPolicy(b.Spec.ImagePullPolicy),
+			Command:         []string{"sh", "-c", backupContent},
+			VolumeMounts:    backupMounts,
+			Resources:       resources,
+		},
+	}
+	if rf.Spec.Auth.SecretPath != "" {
+		steps[0].Env = append(steps[0].Env, corev1.EnvVar{
+			Name: redisPasswordEnv,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: rf.Spec.Auth.SecretPath,
+					},
+					Key: "password",
+				},
+			},
+		})
+	}
+
+	if encryption := b.Spec.Encryption; encryption != nil {
+		encryptContent := fmt.Sprintf(`set -e
+openssl enc -aes-256-cbc -salt -pbkdf2 -in %[1]s -out %[2]s.tmp -pass env:%[3]s
+mv %[2]s.tmp %[2]s
+sed -i "s/^size=.*/size=$(wc -c < %[2]s | tr -d ' ')/" %[4]s`, rdb, artifact, backupEncryptionKeyEnv, metadata)
+		steps = append(steps, corev1.Container{
+			Name:            "encrypt",
+			Image:           encryption.Image,
+			ImagePullPolicy: util2.PullPolicy(encryption.ImagePullPolicy),
+			Command:         []string{"sh", "-c", encryptContent},
+			Env:             []corev1.EnvVar{generateEncryptionKeyEnv(encryption.SecretName, encryption.KeyID)},
+			VolumeMounts:    volumeMounts,
+			Resources:       resources,
+		})
+	}
+
+	if s3 := b.Spec.Storage.S3; s3 != nil {
+		uploadContent := fmt.Sprintf(`set -e
+aws --endpoint-url %s s3 cp %s s3://%s/%s`, s3.Endpoint, artifact, s3.Bucket, util2.GetBackupObjectKey(b))
+		steps = append(steps, corev1.Container{
+			Name:            "upload",
+			Image:           s3.Image,
+			ImagePullPolicy: util2.PullPolicy(s3.ImagePullPolicy),
+			Command:         []string{"sh", "-c", uploadContent},
+			Env:             util2.GenerateS3Env(s3),
+			VolumeMounts:    volumeMounts,
+			Resources:       resources,
+		})
+	}
+
+	last := &steps[len(steps)-1]
+	last.Command[2] = fmt.Sprintf("%s\ncp %s /dev/termination-log", last.Command[2], metadata)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            util2.GetBackupJobName(b),
+			Namespace:       b.Namespace,
+			Labels:          labels,
+			OwnerReferences: ownerRefs,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					NodeName:         nodeName,
+					ImagePullSecrets: rf.Spec.Redis.ImagePullSecrets,
+					SecurityContext:  rf.Spec.Redis.SecurityContext,
+					InitContainers:   steps[:len(steps)-1],
+					Containers:       steps[len(steps)-1:],
+					Volumes:          volumes,
+				},
+			},
+		},
+	}
+}
+
+func generateEncryptionKeyEnv(secretName, keyID string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: backupEncryptionKeyEnv,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: secretName,
+				},
+				Key: keyID,
+			},
+		},
+	}
+}
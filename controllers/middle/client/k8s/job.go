@@ -0,0 +1,113 @@
+package k8s
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Job the client that knows how to interact with kubernetes to manage them
+type Job interface {
+	// GetJob get Job from kubernetes with namespace and name
+	GetJob(namespace string, name string) (*batchv1.Job, error)
+	// GetJobPods will retrieve the pods created by a given Job
+	GetJobPods(namespace string, name string) (*corev1.PodList, error)
+	// CreateJob will create the given Job
+	CreateJob(namespace string, job *batchv1.Job) error
+	// CreateIfNotExistsJob create Job if it does not exist
+	CreateIfNotExistsJob(namespace string, job *batchv1.Job) error
+	// DeleteJob will delete the given Job and its pods
+	DeleteJob(namespace string, name string) error
+	// ListJobs get set of Job on a given namespace
+	ListJobs(namespace string) (*batchv1.JobList, error)
+}
+
+// JobOption is the Job client implementation using API calls to kubernetes.
+type JobOption struct {
+	client client.Client
+	logger logr.Logger
+}
+
+// NewJob returns a new Job client.
+func NewJob(kubeClient client.Client, logger logr.Logger) Job {
+	logger = logger.WithValues("service", "k8s.job")
+	return &JobOption{
+		client: kubeClient,
+		logger: logger,
+	}
+}
+
+// GetJob implement the Job.Interface
+func (j *JobOption) GetJob(namespace string, name string) (*batchv1.Job, error) {
+	job := &batchv1.Job{}
+	err := j.client.Get(context.TODO(), types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, job)
+	if err != nil {
+		return nil, err
+	}
+	return job, err
+}
+
+// GetJobPods implement the Job.Interface
+func (j *JobOption) GetJobPods(namespace string, name string) (*corev1.PodList, error) {
+	job, err := j.GetJob(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	labelSelector := labels.SelectorFromSet(job.Spec.Selector.MatchLabels)
+	foundPods := &corev1.PodList{}
+	err = j.client.List(context.TODO(), foundPods, &client.ListOptions{Namespace: namespace, LabelSelector: labelSelector})
+	return foundPods, err
+}
+
+// CreateJob implement the Job.Interface
+func (j *JobOption) CreateJob(namespace string, job *batchv1.Job) error {
+	err := j.client.Create(context.TODO(), job)
+	if err != nil {
+		return err
+	}
+	j.logger.WithValues("namespace", namespace, "job", job.Name).Info("job created")
+	return nil
+}
+
+// CreateIfNotExistsJob implement the Job.Interface
+func (j *JobOption) CreateIfNotExistsJob(namespace string, job *batchv1.Job) error {
+	if _, err := j.GetJob(namespace, job.Name); err != nil {
+		// If no resource we need to create.
+		if errors.IsNotFound(err) {
+			return j.CreateJob(namespace, job)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteJob implement the Job.Interface
+func (j *JobOption) DeleteJob(namespace string, name string) error {
+	job := &batchv1.Job{}
+	if err := j.client.Get(context.TODO(), types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, job); err != nil {
+		return err
+	}
+	return j.client.Delete(context.TODO(), job, client.PropagationPolicy("Background"))
+}
+
+// ListJobs implement the Job.Interface
+func (j *JobOption) ListJobs(namespace string) (*batchv1.JobList, error) {
+	jobs := &batchv1.JobList{}
+	listOps := &client.ListOptions{
+		Namespace: namespace,
+	}
+	err := j.client.List(context.TODO(), jobs, listOps)
+	return jobs, err
+}
@@ -15,6 +15,10 @@ type Services interface {
 	Deployment
 	StatefulSet
 	Secret
+	Job
+	PersistentVolumeClaim
+	RedisFailover
+	RedisBackup
 }
 
 type services struct {
@@ -26,18 +30,26 @@ type services struct {
 	Deployment
 	StatefulSet
 	Secret
+	Job
+	PersistentVolumeClaim
+	RedisFailover
+	RedisBackup
 }
 
 // New returns a new Kubernetes client set.
 func New(kubecli client.Client, logger logr.Logger) Services {
 	return &services{
-		ConfigMap:           NewConfigMap(kubecli, logger),
-		Pod:                 NewPod(kubecli, logger),
-		PodDisruptionBudget: NewPodDisruptionBudget(kubecli, logger),
-		Service:             NewService(kubecli, logger),
-		NameSpaces:          NewNameSpaces(logger),
-		Deployment:          NewDeployment(kubecli, logger),
-		StatefulSet:         NewStatefulSet(kubecli, logger),
-		Secret:              NewSecret(kubecli,logger),
+		ConfigMap:             NewConfigMap(kubecli, logger),
+		Pod:                   NewPod(kubecli, logger),
+		PodDisruptionBudget:   NewPodDisruptionBudget(kubecli, logger),
+		Service:               NewService(kubecli, logger),
+		NameSpaces:            NewNameSpaces(logger),
+		Deployment:            NewDeployment(kubecli, logger),
+		StatefulSet:           NewStatefulSet(kubecli, logger),
+		Secret:                NewSecret(kubecli, logger),
+		Job:                   NewJob(kubecli, logger),
+		PersistentVolumeClaim: NewPersistentVolumeClaim(kubecli, logger),
+		RedisFailover:         NewRedisFailover(kubecli, logger),
+		RedisBackup:           NewRedisBackup(kubecli, logger),
 	}
 }
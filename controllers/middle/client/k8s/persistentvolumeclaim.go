@@ -0,0 +1,111 @@
+package k8s
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PersistentVolumeClaim the client that knows how to interact with kubernetes to manage them
+type PersistentVolumeClaim interface {
+	// GetPersistentVolumeClaim get PersistentVolumeClaim from kubernetes with namespace and name
+	GetPersistentVolumeClaim(namespace string, name string) (*corev1.PersistentVolumeClaim, error)
+	// CreatePersistentVolumeClaim will create the given PersistentVolumeClaim
+	CreatePersistentVolumeClaim(namespace string, pvc *corev1.PersistentVolumeClaim) error
+	// CreateIfNotExistsPersistentVolumeClaim create PersistentVolumeClaim if it does not exist
+	CreateIfNotExistsPersistentVolumeClaim(namespace string, pvc *corev1.PersistentVolumeClaim) error
+	// UpdatePersistentVolumeClaim will update the given PersistentVolumeClaim
+	UpdatePersistentVolumeClaim(namespace string, pvc *corev1.PersistentVolumeClaim) error
+	// DeletePersistentVolumeClaim will delete the given PersistentVolumeClaim
+	DeletePersistentVolumeClaim(namespace string, name string) error
+	// ListPersistentVolumeClaims get set of PersistentVolumeClaim on a given namespace matching the labels
+	ListPersistentVolumeClaims(namespace string, matchLabels map[string]string) (*corev1.PersistentVolumeClaimList, error)
+}
+
+// PersistentVolumeClaimOption is the PersistentVolumeClaim client implementation using API calls to kubernetes.
+type PersistentVolumeClaimOption struct {
+	client client.Client
+	logger logr.Logger
+}
+
+// NewPersistentVolumeClaim returns a new PersistentVolumeClaim client.
+func NewPersistentVolumeClaim(kubeClient client.Client, logger logr.Logger) PersistentVolumeClaim {
+	logger = logger.WithValues("service", "k8s.persistentVolumeClaim")
+	return &PersistentVolumeClaimOption{
+		client: kubeClient,
+		logger: logger,
+	}
+}
+
+// GetPersistentVolumeClaim implement the PersistentVolumeClaim.Interface
+func (p *PersistentVolumeClaimOption) GetPersistentVolumeClaim(namespace string, name string) (*corev1.PersistentVolumeClaim, error) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := p.client.Get(context.TODO(), types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, pvc)
+	if err != nil {
+		return nil, err
+	}
+	return pvc, err
+}
+
+// CreatePersistentVolumeClaim implement the PersistentVolumeClaim.Interface
+func (p *PersistentVolumeClaimOption) CreatePersistentVolumeClaim(namespace string, pvc *corev1.PersistentVolumeClaim) error {
+	err := p.client.Create(context.TODO(), pvc)
+	if err != nil {
+		return err
+	}
+	p.logger.WithValues("namespace", namespace, "persistentVolumeClaim", pvc.Name).Info("persistentVolumeClaim created")
+	return nil
+}
+
+// CreateIfNotExistsPersistentVolumeClaim implement the PersistentVolumeClaim.Interface
+func (p *PersistentVolumeClaimOption) CreateIfNotExistsPersistentVolumeClaim(namespace string, pvc *corev1.PersistentVolumeClaim) error {
+	if _, err := p.GetPersistentVolumeClaim(namespace, pvc.Name); err != nil {
+		// If no resource we need to create.
+		if errors.IsNotFound(err) {
+			return p.CreatePersistentVolumeClaim(namespace, pvc)
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdatePersistentVolumeClaim implement the PersistentVolumeClaim.Interface
+func (p *PersistentVolumeClaimOption) UpdatePersistentVolumeClaim(namespace string, pvc *corev1.PersistentVolumeClaim) error {
+	err := p.client.Update(context.TODO(), pvc)
+	if err != nil {
+		return err
+	}
+	p.logger.WithValues("namespace", namespace, "persistentVolumeClaim", pvc.Name).Info("persistentVolumeClaim updated")
+	return nil
+}
+
+// DeletePersistentVolumeClaim implement the PersistentVolumeClaim.Interface
+func (p *PersistentVolumeClaimOption) DeletePersistentVolumeClaim(namespace string, name string) error {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := p.client.Get(context.TODO(), types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, pvc); err != nil {
+		return err
+	}
+	return p.client.Delete(context.TODO(), pvc)
+}
+
+// ListPersistentVolumeClaims implement the PersistentVolumeClaim.Interface
+func (p *PersistentVolumeClaimOption) ListPersistentVolumeClaims(namespace string, matchLabels map[string]string) (*corev1.PersistentVolumeClaimList, error) {
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	listOps := &client.ListOptions{
+		Namespace:     namespace,
+		LabelSelector: labels.SelectorFromSet(matchLabels),
+	}
+	err := p.client.List(context.TODO(), pvcs, listOps)
+	return pvcs, err
+}
@@ -0,0 +1,100 @@
+package k8s
+
+import (
+	"context"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RedisBackup the client that knows how to interact with kubernetes to manage them
+type RedisBackup interface {
+	// GetRedisBackup get RedisBackup from kubernetes with namespace and name
+	GetRedisBackup(namespace string, name string) (*middlev1alpha1.RedisBackup, error)
+	// CreateRedisBackup will create the given RedisBackup
+	CreateRedisBackup(namespace string, backup *middlev1alpha1.RedisBackup) error
+	// UpdateRedisBackup will update the given RedisBackup
+	UpdateRedisBackup(namespace string, backup *middlev1alpha1.RedisBackup) error
+	// DeleteRedisBackup will delete the given RedisBackup
+	DeleteRedisBackup(namespace string, name string) error
+	// ListRedisBackups get set of RedisBackup on a given namespace matching the labels
+	ListRedisBackups(namespace string, matchLabels map[string]string) (*middlev1alpha1.RedisBackupList, error)
+}
+
+// RedisBackupOption is the RedisBackup client implementation using API calls to kubernetes.
+type RedisBackupOption struct {
+	client client.Client
+	logger logr.Logger
+}
+
+// NewRedisBackup returns a new RedisBackup client.
+func NewRedisBackup(kubeClient client.Client, logger logr.Logger) RedisBackup {
+	logger = logger.WithValues("service", "k8s.redisBackup")
+	return &RedisBackupOption{
+		client: kubeClient,
+		logger: logger,
+	}
+}
+
+// GetRedisBackup implement the RedisBackup.Interface
+func (r *RedisBackupOption) GetRedisBackup(namespace string, name string) (*middlev1alpha1.RedisBackup, error) {
+	backup := &middlev1alpha1.RedisBackup{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, backup)
+	if err != nil {
+		return nil, err
+	}
+	return backup, err
+}
+
+// CreateRedisBackup implement the RedisBackup.Interface
+func (r *RedisBackupOption) CreateRedisBackup(namespace string, backup *middlev1alpha1.RedisBackup) error {
+	err := r.client.Create(context.TODO(), backup)
+	if err != nil {
+		return err
+	}
+	r.logger.WithValues("namespace", namespace, "redisBackup", backup.Name).Info("redisBackup created")
+	return nil
+}
+
+// UpdateRedisBackup implement the RedisBackup.Interface
+func (r *RedisBackupOption) UpdateRedisBackup(namespace string, backup *middlev1alpha1.RedisBackup) error {
+	err := r.client.Update(context.TODO(), backup)
+	if err != nil {
+		return err
+	}
+	r.logger.WithValues("namespace", namespace, "redisBackup", backup.Name).Info("redisBackup updated")
+	return nil
+}
+
+// DeleteRedisBackup implement the RedisBackup.Interface
+func (r *RedisBackupOption) DeleteRedisBackup(namespace string, name string) error {
+	backup := &middlev1alpha1.RedisBackup{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, backup); err != nil {
+		return err
+	}
+	if err := r.client.Delete(context.TODO(), backup); err != nil {
+		return err
+	}
+	r.logger.WithValues("namespace", namespace, "redisBackup", name).Info("redisBackup deleted")
+	return nil
+}
+
+// ListRedisBackups implement the RedisBackup.Interface
+func (r *RedisBackupOption) ListRedisBackups(namespace string, matchLabels map[string]string) (*middlev1alpha1.RedisBackupList, error) {
+	backups := &middlev1alpha1.RedisBackupList{}
+	listOps := &client.ListOptions{
+		Namespace:     namespace,
+		LabelSelector: labels.SelectorFromSet(matchLabels),
+	}
+	err := r.client.List(context.TODO(), backups, listOps)
+	return backups, err
+}
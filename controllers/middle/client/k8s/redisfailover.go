@@ -0,0 +1,53 @@
+package k8s
+
+import (
+	"context"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RedisFailover the client that knows how to read RedisFailover instances from kubernetes
+type RedisFailover interface {
+	// GetRedisFailover get RedisFailover from kubernetes with namespace and name
+	GetRedisFailover(namespace string, name string) (*middlev1alpha1.RedisFailover, error)
+	// ListRedisFailovers get set of RedisFailover on a given namespace
+	ListRedisFailovers(namespace string) (*middlev1alpha1.RedisFailoverList, error)
+}
+
+// RedisFailoverOption is the RedisFailover client implementation using API calls to kubernetes.
+type RedisFailoverOption struct {
+	client client.Client
+	logger logr.Logger
+}
+
+// NewRedisFailover returns a new RedisFailover client.
+func NewRedisFailover(kubeClient client.Client, logger logr.Logger) RedisFailover {
+	logger = logger.WithValues("service", "k8s.redisFailover")
+	return &RedisFailoverOption{
+		client: kubeClient,
+		logger: logger,
+	}
+}
+
+// GetRedisFailover implement the RedisFailover.Interface
+func (r *RedisFailoverOption) GetRedisFailover(namespace string, name string) (*middlev1alpha1.RedisFailover, error) {
+	rf := &middlev1alpha1.RedisFailover{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, rf)
+	if err != nil {
+		return nil, err
+	}
+	return rf, err
+}
+
+// ListRedisFailovers implement the RedisFailover.Interface
+func (r *RedisFailoverOption) ListRedisFailovers(namespace string) (*middlev1alpha1.RedisFailoverList, error) {
+	rfs := &middlev1alpha1.RedisFailoverList{}
+	err := r.client.List(context.TODO(), rfs, client.InNamespace(namespace))
+	return rfs, err
+}
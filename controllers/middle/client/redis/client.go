@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -20,13 +21,56 @@ type Client interface {
 	ResetSentinel(ip string, auth *util.AuthConfig) error
 	GetSlaveMasterIP(ip string, auth *util.AuthConfig) (string, error)
 	IsMaster(ip string, auth *util.AuthConfig) (bool, error)
-	MonitorRedis(ip string, monitor string, quorum string, auth *util.AuthConfig) error
+	MonitorRedis(ip string, monitor string, monitorPort string, quorum string, auth *util.AuthConfig) error
+	RemoveMonitor(ip string, auth *util.AuthConfig) error
 	MakeMaster(ip string, auth *util.AuthConfig) error
-	MakeSlaveOf(ip string, masterIP string, auth *util.AuthConfig) error
-	GetSentinelMonitor(ip string, auth *util.AuthConfig) (string, error)
-	SetCustomSentinelConfig(ip string, configs []string, auth *util.AuthConfig) error
+	MakeSlaveOf(ip string, masterIP string, masterPort string, auth *util.AuthConfig) error
+	GetSentinelMonitor(ip string, auth *util.AuthConfig) (string, string, error)
+	GetSentinelMasterAddress(ip string, auth *util.AuthConfig) (string, string, error)
+	SetCustomSentinelConfig(ip string, configs map[string]string, auth *util.AuthConfig) error
+	GetSentinelMasterConfig(ip string, auth *util.AuthConfig) (map[string]string, error)
 	SetCustomRedisConfig(ip string, configs map[string]string, auth *util.AuthConfig) error
 	GetAllRedisConfig(rClient *rediscli.Client) (map[string]string, error)
+	BackgroundSave(ip string, auth *util.AuthConfig) error
+	GetRDBSaveStatus(ip string, auth *util.AuthConfig) (*RDBSaveStatus, error)
+	GetReplicationInfo(ip string, auth *util.AuthConfig) (*ReplicationInfo, error)
+	GetServerInfo(ip string, auth *util.AuthConfig) (*ServerInfo, error)
+	GetSentinelServerInfo(ip string, auth *util.AuthConfig) (*ServerInfo, error)
+	GetKeyspace(ip string, auth *util.AuthConfig) (map[string]int64, error)
+	SentinelFailover(ip string, auth *util.AuthConfig) error
+	SetSentinelAnnounceAddress(ip string, announceIP string, announcePort string, auth *util.AuthConfig) error
+	SetSentinelCommandRenames(ip string, renames map[string]string, auth *util.AuthConfig) error
+}
+
+// RDBSaveStatus is the state of the background RDB save reported by INFO persistence
+type RDBSaveStatus struct {
+	InProgress   bool
+	LastStatusOK bool
+	LastSaveTime int64
+}
+
+// ServerInfo is the identity of a redis reported by INFO server
+type ServerInfo struct {
+	// RunID changes every time redis restarts
+	RunID   string
+	Version string
+}
+
+// ReplicationInfo is the replication state reported by INFO replication
+type ReplicationInfo struct {
+	Master bool
+	// MasterLinkUp reports whether a replica is connected to its master
+	MasterLinkUp bool
+	// Offset is the master_repl_offset of a master, the slave_repl_offset a replica has processed
+	Offset int64
+	// MasterHost and MasterPort are the address a replica replicates from
+	MasterHost string
+	MasterPort string
+	// SecondOffset is the second_repl_offset of a master promoted from a replica, the offset its own
+	// history starts from, -1 when it never replicated since it started
+	SecondOffset int64
+	// Priority is the slave-priority of a replica, 0 keeps it from being promoted
+	Priority int
 }
 
 type client struct {
@@ -43,13 +87,11 @@ const (
 	sentinelStatusREString  = "status=([a-z]+)"
 	redisMasterHostREString = "master_host:([0-9a-zA-Z:.]+)"
 	redisRoleMaster         = "role:master"
+	bgsaveInProgressError   = "Background save already in progress"
 	redisPort               = "6379"
 	sentinelPort            = "26379"
-	masterName              = "mymaster"
-
-	defaultDownAfterMilliseconds = "5000"
-	defaultFailovertimeout       = "3000"
-	defaultParallelSyncs         = "2"
+	// defaultSlavePriority is the slave-priority of redis, INFO replication only reports it on replicas
+	defaultSlavePriority = 100
 )
 
 var (
@@ -61,17 +103,17 @@ var (
 
 // GetNumberSentinelsInMemory return the number of sentinels that the requested sentinel has
 func (c *client) GetNumberSentinelsInMemory(ip string, auth *util.AuthConfig) (int32, error) {
-	options := c.setOptions(ip, sentinelPort, auth)
-	rClient := rediscli.NewClient(options)
+	rClient := c.newClient(ip, sentinelPort, auth)
 	defer rClient.Close()
 	info, err := rClient.Info("sentinel").Result()
 	if err != nil {
 		return 0, err
 	}
-	if err2 := isSentinelReady(info); err2 != nil {
+	masterInfo := getSentinelMasterInfo(info, sentinelMasterName(auth))
+	if err2 := isSentinelReady(masterInfo); err2 != nil {
 		return 0, err2
 	}
-	match := sentinelNumberRE.FindStringSubmatch(info)
+	match := sentinelNumberRE.FindStringSubmatch(masterInfo)
 	if len(match) == 0 {
 		return 0, errors.New("seninel regex not found")
 	}
@@ -84,19 +126,18 @@ func (c *client) GetNumberSentinelsInMemory(ip string, auth *util.AuthConfig) (i
 
 // GetNumberSentinelsInMemory return the number of sentinels that the requested sentinel has
 func (c *client) GetNumberSentinelSlavesInMemory(ip string, auth *util.AuthConfig) (int32, error) {
-	options := c.setOptions(ip, sentinelPort, auth)
-	rClient := rediscli.NewClient(options)
+	rClient := c.newClient(ip, sentinelPort, auth)
 	defer rClient.Close()
 	info, err := rClient.Info("sentinel").Result()
 	if err != nil {
 		return 0, err
 	}
 
-	if err = isSentinelReady(info); err != nil {
+	if err = isSentinelReady(getSentinelMasterInfo(info, sentinelMasterName(auth))); err != nil {
 		return 0, err
 	}
 
-	cmd := rediscli.NewSliceCmd("sentinel", "slaves", masterName)
+	cmd := rediscli.NewSliceCmd("sentinel", "slaves", sentinelMasterName(auth))
 	rClient.Process(cmd)
 	slaveInfoBlobs, err := cmd.Result()
 	if err != nil {
@@ -127,6 +168,25 @@ func slaveInfoFieldByName(name string, slaveInfoBlob interface{}) string {
 	return ""
 }
 
+// getSentinelMasterInfo returns the line INFO sentinel reports about the given master, a sentinel
+// shared by several instances monitors one master for each of them
+func getSentinelMasterInfo(info string, masterName string) string {
+	for _, line := range strings.Split(info, "\n") {
+		if strings.Contains(line, fmt.Sprintf(":name=%s,", masterName)) {
+			return strings.TrimSpace(line)
+		}
+	}
+	return ""
+}
+
+// sentinelMasterName returns the name the sentinels monitor the master under
+func sentinelMasterName(auth *util.AuthConfig) string {
+	if auth.MasterName != "" {
+		return auth.MasterName
+	}
+	return util.DefaultSentinelMasterName
+}
+
 func isSentinelReady(info string) error {
 	matchStatus := sentinelStatusRE.FindStringSubmatch(info)
 	if len(matchStatus) == 0 || matchStatus[1] != "ok" {
@@ -135,12 +195,12 @@ func isSentinelReady(info string) error {
 	return nil
 }
 
-// ResetSentinel sends a sentinel reset * for the given sentinel
+// ResetSentinel resets the state the given sentinel keeps about the master, leaving the other masters
+// of a shared sentinel alone
 func (c *client) ResetSentinel(ip string, auth *util.AuthConfig) error {
-	options := c.setOptions(ip, sentinelPort, auth)
-	rClient := rediscli.NewClient(options)
+	rClient := c.newClient(ip, sentinelPort, auth)
 	defer rClient.Close()
-	cmd := rediscli.NewIntCmd("SENTINEL", "reset", "*")
+	cmd := rediscli.NewIntCmd("SENTINEL", "reset", sentinelMasterName(auth))
 	rClient.Process(cmd)
 	_, err := cmd.Result()
 	if err != nil {
@@ -151,8 +211,7 @@ func (c *client) ResetSentinel(ip string, auth *util.AuthConfig) error {
 
 // GetSlaveMasterIP returns the master of the given redis, or nil if it's master
 func (c *client) GetSlaveMasterIP(ip string, auth *util.AuthConfig) (string, error) {
-	options := c.setOptions(ip, redisPort, auth)
-	rClient := rediscli.NewClient(options)
+	rClient := c.newClient(ip, redisPort, auth)
 	defer rClient.Close()
 	info, err := rClient.Info("replication").Result()
 	if err != nil {
@@ -166,8 +225,7 @@ func (c *client) GetSlaveMasterIP(ip string, auth *util.AuthConfig) (string, err
 }
 
 func (c *client) IsMaster(ip string, auth *util.AuthConfig) (bool, error) {
-	options := c.setOptions(ip, redisPort, auth)
-	rClient := rediscli.NewClient(options)
+	rClient := c.newClient(ip, redisPort, auth)
 	defer rClient.Close()
 	info, err := rClient.Info("replication").Result()
 	if err != nil {
@@ -176,14 +234,14 @@ func (c *client) IsMaster(ip string, auth *util.AuthConfig) (bool, error) {
 	return strings.Contains(info, redisRoleMaster), nil
 }
 
-func (c *client) MonitorRedis(ip string, monitor string, quorum string, auth *util.AuthConfig) error {
-	options := c.setOptions(ip, sentinelPort, auth)
-	rClient := rediscli.NewClient(options)
+func (c *client) MonitorRedis(ip string, monitor string, monitorPort string, quorum string, auth *util.AuthConfig) error {
+	rClient := c.newClient(ip, sentinelPort, auth)
 	defer rClient.Close()
+	masterName := sentinelMasterName(auth)
 	cmd := rediscli.NewBoolCmd("SENTINEL", "REMOVE", masterName)
 	rClient.Process(cmd)
 	// We'll continue even if it fails, the priority is to have the redises monitored
-	cmd = rediscli.NewBoolCmd("SENTINEL", "MONITOR", masterName, monitor, redisPort, quorum)
+	cmd = rediscli.NewBoolCmd("SENTINEL", "MONITOR", masterName, monitor, monitorPort, quorum)
 	rClient.Process(cmd)
 	_, err := cmd.Result()
 	if err != nil {
@@ -196,29 +254,23 @@ func (c *client) MonitorRedis(ip string, monitor string, quorum string, auth *ut
 			return err
 		}
 	}
+	return c.applySentinelCommandRenames(masterName, auth.SentinelCommandRenames, rClient)
+}
 
-	sCmd := rediscli.NewStatusCmd("SENTINEL", "SET", masterName, "down-after-milliseconds", defaultDownAfterMilliseconds)
-	rClient.Process(sCmd)
-	if err = sCmd.Err(); err != nil {
-		return err
-	}
-	sCmd = rediscli.NewStatusCmd("SENTINEL", "SET", masterName, "failover-timeout", defaultFailovertimeout)
-	rClient.Process(sCmd)
-	if err = sCmd.Err(); err != nil {
-		return err
-	}
-	sCmd = rediscli.NewStatusCmd("SENTINEL", "SET", masterName, "parallel-syncs", defaultParallelSyncs)
-	rClient.Process(sCmd)
-	if err = sCmd.Err(); err != nil {
+// RemoveMonitor has the given sentinel stop monitoring the master, a master it does not monitor is not an error
+func (c *client) RemoveMonitor(ip string, auth *util.AuthConfig) error {
+	rClient := c.newClient(ip, sentinelPort, auth)
+	defer rClient.Close()
+	cmd := rediscli.NewStatusCmd("SENTINEL", "REMOVE", sentinelMasterName(auth))
+	rClient.Process(cmd)
+	if err := cmd.Err(); err != nil && !strings.Contains(err.Error(), "No such master") {
 		return err
 	}
-
 	return nil
 }
 
 func (c *client) MakeMaster(ip string, auth *util.AuthConfig) error {
-	options := c.setOptions(ip, redisPort, auth)
-	rClient := rediscli.NewClient(options)
+	rClient := c.newClient(ip, redisPort, auth)
 	defer rClient.Close()
 	if res := rClient.SlaveOf("NO", "ONE"); res.Err() != nil {
 		return res.Err()
@@ -226,50 +278,81 @@ func (c *client) MakeMaster(ip string, auth *util.AuthConfig) error {
 	return nil
 }
 
-func (c *client) MakeSlaveOf(ip string, masterIP string, auth *util.AuthConfig) error {
-	options := c.setOptions(ip, redisPort, auth)
-	rClient := rediscli.NewClient(options)
+func (c *client) MakeSlaveOf(ip string, masterIP string, masterPort string, auth *util.AuthConfig) error {
+	rClient := c.newClient(ip, redisPort, auth)
 	defer rClient.Close()
-	if res := rClient.SlaveOf(masterIP, redisPort); res.Err() != nil {
+	if res := rClient.SlaveOf(masterIP, masterPort); res.Err() != nil {
 		return res.Err()
 	}
 	return nil
 }
 
-func (c *client) GetSentinelMonitor(ip string, auth *util.AuthConfig) (string, error) {
-	options := c.setOptions(ip, sentinelPort, auth)
-	rClient := rediscli.NewClient(options)
+// GetSentinelMonitor returns the address of the master the given sentinel monitors
+func (c *client) GetSentinelMonitor(ip string, auth *util.AuthConfig) (string, string, error) {
+	rClient := c.newClient(ip, sentinelPort, auth)
 	defer rClient.Close()
-	cmd := rediscli.NewSliceCmd("SENTINEL", "master", masterName)
+	cmd := rediscli.NewSliceCmd("SENTINEL", "master", sentinelMasterName(auth))
 	rClient.Process(cmd)
 	res, err := cmd.Result()
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	masterIP := res[3].(string)
-	return masterIP, nil
+	masterPort := res[5].(string)
+	return masterIP, masterPort, nil
+}
+
+// GetSentinelMasterAddress returns the address the sentinel currently considers the master,
+// as answered by SENTINEL get-master-addr-by-name
+func (c *client) GetSentinelMasterAddress(ip string, auth *util.AuthConfig) (string, string, error) {
+	rClient := c.newClient(ip, sentinelPort, auth)
+	defer rClient.Close()
+	cmd := rediscli.NewStringSliceCmd("SENTINEL", "get-master-addr-by-name", sentinelMasterName(auth))
+	rClient.Process(cmd)
+	res, err := cmd.Result()
+	if err != nil {
+		return "", "", err
+	}
+	if len(res) != 2 {
+		return "", "", fmt.Errorf("sentinel %s does not know the master %s", ip, sentinelMasterName(auth))
+	}
+	return res[0], res[1], nil
 }
 
-func (c *client) SetCustomSentinelConfig(ip string, configs []string, auth *util.AuthConfig) error {
-	options := c.setOptions(ip, sentinelPort, auth)
-	rClient := rediscli.NewClient(options)
+func (c *client) SetCustomSentinelConfig(ip string, configs map[string]string, auth *util.AuthConfig) error {
+	rClient := c.newClient(ip, sentinelPort, auth)
 	defer rClient.Close()
 
-	for _, config := range configs {
-		param, value, err := c.getConfigParameters(config)
-		if err != nil {
-			return err
-		}
-		if err := c.applySentinelConfig(param, value, rClient); err != nil {
+	for param, value := range configs {
+		if err := c.applySentinelConfig(sentinelMasterName(auth), param, value, rClient); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// GetSentinelMasterConfig returns the fields SENTINEL MASTER reports about the monitored master,
+// among them the quorum, down-after-milliseconds, failover-timeout and parallel-syncs
+func (c *client) GetSentinelMasterConfig(ip string, auth *util.AuthConfig) (map[string]string, error) {
+	rClient := c.newClient(ip, sentinelPort, auth)
+	defer rClient.Close()
+	cmd := rediscli.NewSliceCmd("SENTINEL", "MASTER", sentinelMasterName(auth))
+	rClient.Process(cmd)
+	res, err := cmd.Result()
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]string)
+	for i := 0; i+1 < len(res); i += 2 {
+		key, _ := res[i].(string)
+		value, _ := res[i+1].(string)
+		fields[key] = value
+	}
+	return fields, nil
+}
+
 func (c *client) SetCustomRedisConfig(ip string, configs map[string]string, auth *util.AuthConfig) error {
-	options := c.setOptions(ip, redisPort, auth)
-	rClient := rediscli.NewClient(options)
+	rClient := c.newClient(ip, redisPort, auth)
 	defer rClient.Close()
 
 	for param, value := range configs {
@@ -299,23 +382,225 @@ func (c *client) GetAllRedisConfig(rClient *rediscli.Client) (map[string]string,
 	return valMap, nil
 }
 
+// BackgroundSave asks the given redis to fork and write its RDB file, a save already running is not an error
+func (c *client) BackgroundSave(ip string, auth *util.AuthConfig) error {
+	rClient := c.newClient(ip, redisPort, auth)
+	defer rClient.Close()
+	if err := rClient.BgSave().Err(); err != nil && !strings.Contains(err.Error(), bgsaveInProgressError) {
+		return err
+	}
+	return nil
+}
+
+// GetRDBSaveStatus returns the progress and result of the last background save of the given redis
+func (c *client) GetRDBSaveStatus(ip string, auth *util.AuthConfig) (*RDBSaveStatus, error) {
+	rClient := c.newClient(ip, redisPort, auth)
+	defer rClient.Close()
+	info, err := rClient.Info("persistence").Result()
+	if err != nil {
+		return nil, err
+	}
+	fields := parseInfo(info)
+	lastSave, err := strconv.ParseInt(fields["rdb_last_save_time"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("malformed rdb_last_save_time: %v", err)
+	}
+	return &RDBSaveStatus{
+		InProgress:   fields["rdb_bgsave_in_progress"] == "1",
+		LastStatusOK: fields["rdb_last_bgsave_status"] == "ok",
+		LastSaveTime: lastSave,
+	}, nil
+}
+
+// GetReplicationInfo returns the role and replication offset of the given redis
+func (c *client) GetReplicationInfo(ip string, auth *util.AuthConfig) (*ReplicationInfo, error) {
+	rClient := c.newClient(ip, redisPort, auth)
+	defer rClient.Close()
+	info, err := rClient.Info("replication").Result()
+	if err != nil {
+		return nil, err
+	}
+	fields := parseInfo(info)
+	replication := &ReplicationInfo{
+		Master:       fields["role"] == "master",
+		MasterLinkUp: fields["master_link_status"] == "up",
+		MasterHost:   fields["master_host"],
+		MasterPort:   fields["master_port"],
+		SecondOffset: -1,
+		Priority:     defaultSlavePriority,
+	}
+	offsetField := "slave_repl_offset"
+	if replication.Master {
+		offsetField = "master_repl_offset"
+	}
+	// a replica still loading its first sync does not report an offset yet
+	if offset, ok := fields[offsetField]; ok {
+		if replication.Offset, err = strconv.ParseInt(offset, 10, 64); err != nil {
+			return nil, fmt.Errorf("malformed %s: %v", offsetField, err)
+		}
+	}
+	if priority, ok := fields["slave_priority"]; ok {
+		if replication.Priority, err = strconv.Atoi(priority); err != nil {
+			return nil, fmt.Errorf("malformed slave_priority: %v", err)
+		}
+	}
+	if offset, ok := fields["second_repl_offset"]; ok {
+		if replication.SecondOffset, err = strconv.ParseInt(offset, 10, 64); err != nil {
+			return nil, fmt.Errorf("malformed second_repl_offset: %v", err)
+		}
+	}
+	return replication, nil
+}
+
+// GetServerInfo returns the run ID and version INFO server reports
+func (c *client) GetServerInfo(ip string, auth *util.AuthConfig) (*ServerInfo, error) {
+	return c.getServerInfo(ip, redisPort, auth)
+}
+
+// GetSentinelServerInfo returns the run ID and version INFO server reports on the given sentinel
+func (c *client) GetSentinelServerInfo(ip string, auth *util.AuthConfig) (*ServerInfo, error) {
+	return c.getServerInfo(ip, sentinelPort, auth)
+}
+
+func (c *client) getServerInfo(ip string, port string, auth *util.AuthConfig) (*ServerInfo, error) {
+	rClient := c.newClient(ip, port, auth)
+	defer rClient.Close()
+	info, err := rClient.Info("server").Result()
+	if err != nil {
+		return nil, err
+	}
+	fields := parseInfo(info)
+	if fields["run_id"] == "" {
+		return nil, errors.New("run_id not found in INFO server")
+	}
+	return &ServerInfo{RunID: fields["run_id"], Version: fields["redis_version"]}, nil
+}
+
+// GetKeyspace returns the number of keys of every non empty database, keyed by db name
+func (c *client) GetKeyspace(ip string, auth *util.AuthConfig) (map[string]int64, error) {
+	rClient := c.newClient(ip, redisPort, auth)
+	defer rClient.Close()
+	info, err := rClient.Info("keyspace").Result()
+	if err != nil {
+		return nil, err
+	}
+	keyspace := make(map[string]int64)
+	// db0:keys=1,expires=0,avg_ttl=0
+	for db, value := range parseInfo(info) {
+		for _, field := range strings.Split(value, ",") {
+			if !strings.HasPrefix(field, "keys=") {
+				continue
+			}
+			keys, err := strconv.ParseInt(strings.TrimPrefix(field, "keys="), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed keyspace of %s: %v", db, err)
+			}
+			keyspace[db] = keys
+		}
+	}
+	return keyspace, nil
+}
+
+// SentinelFailover asks the given sentinel to promote a replica of the monitored master, without
+// waiting for the master to be down
+func (c *client) SentinelFailover(ip string, auth *util.AuthConfig) error {
+	rClient := c.newClient(ip, sentinelPort, auth)
+	defer rClient.Close()
+	cmd := rediscli.NewStatusCmd("SENTINEL", "failover", sentinelMasterName(auth))
+	rClient.Process(cmd)
+	return cmd.Err()
+}
+
+// SetSentinelAnnounceAddress has the given sentinel announce another address to the other sentinels,
+// it requires redis 6.2 or later
+func (c *client) SetSentinelAnnounceAddress(ip string, announceIP string, announcePort string, auth *util.AuthConfig) error {
+	rClient := c.newClient(ip, sentinelPort, auth)
+	defer rClient.Close()
+	cmd := rediscli.NewStatusCmd("SENTINEL", "CONFIG", "SET", "announce-ip", announceIP)
+	rClient.Process(cmd)
+	if err := cmd.Err(); err != nil {
+		return err
+	}
+	cmd = rediscli.NewStatusCmd("SENTINEL", "CONFIG", "SET", "announce-port", announcePort)
+	rClient.Process(cmd)
+	return cmd.Err()
+}
+
+// SetSentinelCommandRenames tells the given sentinel the names redis knows the commands by, a command
+// renamed to itself drops its rename
+func (c *client) SetSentinelCommandRenames(ip string, renames map[string]string, auth *util.AuthConfig) error {
+	rClient := c.newClient(ip, sentinelPort, auth)
+	defer rClient.Close()
+	return c.applySentinelCommandRenames(sentinelMasterName(auth), renames, rClient)
+}
+
+// parseInfo splits the "key:value" lines of an INFO reply into a map
+func parseInfo(info string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if kv := strings.SplitN(line, ":", 2); len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	return fields
+}
+
 func (c *client) applyRedisConfig(parameter string, value string, rClient *rediscli.Client) error {
 	result := rClient.ConfigSet(parameter, value)
 	return result.Err()
 }
 
-func (c *client) applySentinelConfig(parameter string, value string, rClient *rediscli.Client) error {
+func (c *client) applySentinelConfig(masterName string, parameter string, value string, rClient *rediscli.Client) error {
 	cmd := rediscli.NewStatusCmd("SENTINEL", "set", masterName, parameter, value)
 	rClient.Process(cmd)
 	return cmd.Err()
 }
 
-func (c *client) getConfigParameters(config string) (parameter string, value string, err error) {
-	s := strings.Split(config, " ")
-	if len(s) < 2 {
-		return "", "", fmt.Errorf("configuration '%s' malformed", config)
+func (c *client) applySentinelCommandRenames(masterName string, renames map[string]string, rClient *rediscli.Client) error {
+	commands := make([]string, 0, len(renames))
+	for command := range renames {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	for _, command := range commands {
+		cmd := rediscli.NewStatusCmd("SENTINEL", "SET", masterName, "rename-command", command, renames[command])
+		rClient.Process(cmd)
+		if err := cmd.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newClient connects to the redis or sentinel listening on the port, the commands sent to redis are
+// renamed the way the redis.conf the pod started from renamed them
+func (c *client) newClient(ip, port string, auth *util.AuthConfig) *rediscli.Client {
+	rClient := rediscli.NewClient(c.setOptions(ip, port, auth))
+	if port == redisPort {
+		RenameCommands(rClient, auth.ForRedis(ip))
 	}
-	return s[0], strings.Join(s[1:], " "), nil
+	return rClient
+}
+
+// RenameCommands makes the client send the commands under the names of auth.CommandRenames
+func RenameCommands(rClient *rediscli.Client, auth *util.AuthConfig) {
+	if len(auth.CommandRenames) == 0 {
+		return
+	}
+	rClient.WrapProcess(func(process func(cmd rediscli.Cmder) error) func(cmd rediscli.Cmder) error {
+		return func(cmd rediscli.Cmder) error {
+			if args := cmd.Args(); len(args) > 0 {
+				if name, ok := args[0].(string); ok {
+					args[0] = auth.Command(name)
+				}
+			}
+			return process(cmd)
+		}
+	})
 }
 
 func (c *client) setOptions(ip, port string, auth *util.AuthConfig) *rediscli.Options {
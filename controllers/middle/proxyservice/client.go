@@ -1,7 +1,6 @@
 package proxyservice
 
 import (
-	"context"
 
 	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
 	"github.com/DevineLiu/redis-operator/controllers/middle/client/k8s"
@@ -81,13 +80,12 @@ func (r RedisProxyKubeClient) EnsureRedisProxyDeployment(rp *middlev1alpha1.Redi
 		}
 	}
 
-	if rp.Status.IsLastConditionUpgrading() {
+	if rp.Status.IsUpgrading() {
 		_, err := r.K8SService.RolloutRestartDeployment(current_deploy.Namespace, current_deploy.Name)
 		if err != nil {
 			return err
 		}
 		rp.Status.SetReadyCondition("ready")
-		r.StatusWriter.Update(context.TODO(), rp)
 	}
 
 	return nil
@@ -148,7 +146,6 @@ func (r RedisProxyKubeClient) EnsureRedisProxyConfigMap(rp *middlev1alpha1.Redis
 	}
 	if ShoudUpdateConfigmap(cm, old_cm) {
 		rp.Status.SetUpgradingCondition("upgrading configmap")
-		r.StatusWriter.Update(context.TODO(), rp)
 		r.K8SService.UpdateConfigMap(cm.Namespace, cm)
 
 	}
@@ -48,7 +48,7 @@ func generateRedisProxyDeployment(rp *middlev1alpha1.RedisProxy, labels map[stri
 						{
 							Name:            "proxy",
 							Image:           rp.Spec.Image,
-							ImagePullPolicy: pullPolicy(rp.Spec.ImagePullPolicy),
+							ImagePullPolicy: util2.PullPolicy(rp.Spec.ImagePullPolicy),
 							Ports: []corev1.ContainerPort{
 								{
 									Name:          "redis-proxy",
@@ -251,13 +251,6 @@ func getSecurityContext(secctx *corev1.PodSecurityContext) *corev1.PodSecurityCo
 	return nil
 }
 
-func pullPolicy(specPolicy corev1.PullPolicy) corev1.PullPolicy {
-	if specPolicy == "" {
-		return corev1.PullAlways
-	}
-	return specPolicy
-}
-
 func getProxyCommand() []string {
 	return []string{
 		"predixy",
@@ -0,0 +1,329 @@
+package redisbackup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/backupservice"
+	"github.com/DevineLiu/redis-operator/controllers/middle/client/k8s"
+	"github.com/DevineLiu/redis-operator/controllers/middle/client/redis"
+	"github.com/DevineLiu/redis-operator/controllers/middle/service"
+	util "github.com/DevineLiu/redis-operator/controllers/util"
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// backupMaxReplicaLag is the replication lag in bytes up to which a replica is in sync for a backup
+const backupMaxReplicaLag = 1 << 20
+
+type RedisBackupHandler struct {
+	Logger      logr.Logger
+	Record      record.EventRecorder
+	K8sService  k8s.Services
+	RbServices  backupservice.RedisBackupClient
+	RfChecker   service.RedisFailoverCheck
+	RedisClient redis.Client
+}
+
+// Do drives a RedisBackup through Pending -> Running -> Succeeded/Failed. A running backup first
+// waits for the BGSAVE on the source pod, then for the Job copying the RDB to the backup volume.
+// The status is only changed in memory, the controller writes it once the reconcile is done.
+func (r *RedisBackupHandler) Do(b *middlev1alpha1.RedisBackup) error {
+	if b.Status.IsFinished() {
+		return nil
+	}
+	if err := b.Validate(); err != nil {
+		r.Record.Event(b, v1.EventTypeWarning, "Valiadte", fmt.Sprintf("err: %s", err.Error()))
+		return r.setFailed(b, err.Error())
+	}
+	rf, err := r.K8sService.GetRedisFailover(b.Namespace, b.Spec.RedisFailoverName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return r.setFailed(b, fmt.Sprintf("redisfailover %s not found", b.Spec.RedisFailoverName))
+		}
+		return err
+	}
+	auth, err := r.getAuth(rf)
+	if err != nil {
+		return err
+	}
+
+	r.Logger.WithValues("namespace", b.Namespace, "name", b.Name).V(2).Info("Backup...", "phase", b.Status.Phase)
+	switch b.Status.Phase {
+	case "":
+		now := metav1.Now()
+		b.Status.Phase = middlev1alpha1.RedisBackupPhasePending
+		b.Status.StartTime = &now
+		return nil
+	case middlev1alpha1.RedisBackupPhasePending:
+		return r.startSave(b, rf, auth)
+	case middlev1alpha1.RedisBackupPhaseRunning:
+		if b.Status.JobName == "" {
+			return r.waitSave(b, rf, auth)
+		}
+		return r.checkJob(b)
+	}
+	return nil
+}
+
+func (r *RedisBackupHandler) startSave(b *middlev1alpha1.RedisBackup, rf *middlev1alpha1.RedisFailover, auth *util.AuthConfig) error {
+	source, err := r.getSourcePod(b, rf, auth)
+	if err != nil {
+		return err
+	}
+	if err := r.RedisClient.BackgroundSave(source.Status.PodIP, auth); err != nil {
+		return err
+	}
+	replication, err := r.RedisClient.GetReplicationInfo(source.Status.PodIP, auth)
+	if err != nil {
+		return err
+	}
+	sourceRole := middlev1alpha1.RedisBackupSourceReplica
+	if replication.Master {
+		sourceRole = middlev1alpha1.RedisBackupSourceMaster
+	}
+	r.Record.Event(b, v1.EventTypeNormal, "BackgroundSave", fmt.Sprintf("BGSAVE started on %s %s", sourceRole, source.Name))
+	b.Status.Phase = middlev1alpha1.RedisBackupPhaseRunning
+	b.Status.SourcePod = source.Name
+	b.Status.SourceRole = sourceRole
+	return nil
+}
+
+func (r *RedisBackupHandler) waitSave(b *middlev1alpha1.RedisBackup, rf *middlev1alpha1.RedisFailover, auth *util.AuthConfig) error {
+	source, err := r.K8sService.GetPod(b.Namespace, b.Status.SourcePod)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return r.setFailed(b, fmt.Sprintf("source pod %s is gone", b.Status.SourcePod))
+		}
+		return err
+	}
+	status, err := r.RedisClient.GetRDBSaveStatus(source.Status.PodIP, auth)
+	if err != nil {
+		return err
+	}
+	if status.InProgress {
+		return nil
+	}
+	if !status.LastStatusOK {
+		return r.setFailed(b, fmt.Sprintf("BGSAVE failed on %s, rdb_last_bgsave_status is not ok", source.Name))
+	}
+	keyspace, err := r.RedisClient.GetKeyspace(source.Status.PodIP, auth)
+	if err != nil {
+		return err
+	}
+
+	labels := r.getLabels(b)
+	oRefs := r.createOwnerReferences(b)
+	if b.Spec.Storage.S3 == nil {
+		if err := r.RbServices.EnsureBackupPersistentVolumeClaim(b, labels, oRefs); err != nil {
+			return err
+		}
+	}
+	if err := r.RbServices.EnsureBackupJob(b, rf, source, labels, oRefs); err != nil {
+		return err
+	}
+	b.Status.JobName = util.GetBackupJobName(b)
+	b.Status.Keyspace = keyspace
+	if b.Spec.Encryption != nil {
+		b.Status.EncryptionKeyID = b.Spec.Encryption.KeyID
+	}
+	if b.Spec.Storage.S3 != nil {
+		b.Status.Path = util.GetBackupObjectKey(b)
+	} else {
+		b.Status.PersistentVolumeClaim = util.GetBackupPersistentVolumeClaimName(b)
+		b.Status.Path = util.GetBackupFileName(b)
+	}
+	return nil
+}
+
+func (r *RedisBackupHandler) checkJob(b *middlev1alpha1.RedisBackup) error {
+	job, err := r.K8sService.GetJob(b.Namespace, b.Status.JobName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return r.setFailed(b, fmt.Sprintf("backup job %s is gone", b.Status.JobName))
+		}
+		return err
+	}
+	if job.Status.Succeeded > 0 {
+		message, err := r.getTerminationMessage(b, v1.PodSucceeded)
+		if err != nil {
+			return err
+		}
+		if err := setArtifactMetadata(b, message); err != nil {
+			return r.setFailed(b, fmt.Sprintf("backup verification failed: %v", err))
+		}
+		now := metav1.Now()
+		b.Status.Phase = middlev1alpha1.RedisBackupPhaseSucceeded
+		b.Status.CompletionTime = &now
+		b.Status.Message = ""
+		r.Record.Event(b, v1.EventTypeNormal, "BackupSucceeded", fmt.Sprintf("backup of %d bytes written to %s", b.Status.Size, getArtifactLocation(b)))
+		return nil
+	}
+	if job.Spec.BackoffLimit != nil && job.Status.Failed > *job.Spec.BackoffLimit {
+		// a corrupted artifact fails the job, redis-check-rdb explains why
+		message, _ := r.getTerminationMessage(b, v1.PodFailed)
+		return r.setFailed(b, strings.TrimSpace(fmt.Sprintf("backup job %s failed: %s", job.Name, message)))
+	}
+	return nil
+}
+
+// getTerminationMessage returns the termination message of the backup containers of a pod of the job in the given phase
+func (r *RedisBackupHandler) getTerminationMessage(b *middlev1alpha1.RedisBackup, phase v1.PodPhase) (string, error) {
+	pods, err := r.K8sService.GetJobPods(b.Namespace, b.Status.JobName)
+	if err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != phase {
+			continue
+		}
+		statuses := append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...)
+		for i := len(statuses) - 1; i >= 0; i-- {
+			if terminated := statuses[i].State.Terminated; terminated != nil && terminated.Message != "" {
+				return terminated.Message, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no %s pod found for backup job %s", strings.ToLower(string(phase)), b.Status.JobName)
+}
+
+// setArtifactMetadata records the key=value metadata the backup job wrote once the artifact was verified
+func setArtifactMetadata(b *middlev1alpha1.RedisBackup, message string) error {
+	metadata := map[string]string{}
+	for _, line := range strings.Split(message, "\n") {
+		if kv := strings.SplitN(strings.TrimSpace(line), "=", 2); len(kv) == 2 {
+			metadata[kv[0]] = kv[1]
+		}
+	}
+	size, err := strconv.ParseInt(metadata["size"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed size: %v", err)
+	}
+	rdbVersion, err := strconv.ParseInt(metadata["rdbVersion"], 10, 32)
+	if err != nil {
+		return fmt.Errorf("malformed rdb version: %v", err)
+	}
+	keys, err := strconv.ParseInt(metadata["keys"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed key count: %v", err)
+	}
+	b.Status.Size = size
+	b.Status.RDBVersion = int32(rdbVersion)
+	b.Status.RedisVersion = metadata["redisVersion"]
+	b.Status.Keys = keys
+	return nil
+}
+
+func getArtifactLocation(b *middlev1alpha1.RedisBackup) string {
+	if s3 := b.Spec.Storage.S3; s3 != nil {
+		return fmt.Sprintf("s3://%s/%s", s3.Bucket, b.Status.Path)
+	}
+	return fmt.Sprintf("%s/%s", b.Status.PersistentVolumeClaim, b.Status.Path)
+}
+
+// getSourcePod returns the pod the RDB is taken from: spec.sourcePod when set, else the replica
+// closest to the master among those in sync, so the master does not fork. The master is only used
+// when no replica is in sync.
+func (r *RedisBackupHandler) getSourcePod(b *middlev1alpha1.RedisBackup, rf *middlev1alpha1.RedisFailover, auth *util.AuthConfig) (*v1.Pod, error) {
+	pods, err := r.K8sService.GetStatefulSetPods(rf.Namespace, util.GetRedisName(rf))
+	if err != nil {
+		return nil, err
+	}
+	if b.Spec.SourcePod != "" {
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.Name == b.Spec.SourcePod && pod.Status.Phase == v1.PodRunning {
+				return pod, nil
+			}
+		}
+		return nil, fmt.Errorf("source pod %s is not running", b.Spec.SourcePod)
+	}
+
+	masterIP, err := r.RfChecker.GetMasterIP(rf, auth)
+	if err != nil {
+		return nil, err
+	}
+	master, err := r.RedisClient.GetReplicationInfo(masterIP, auth)
+	if err != nil {
+		return nil, err
+	}
+	redisesIP, err := r.RfChecker.GetRedisesIPs(rf, auth)
+	if err != nil {
+		return nil, err
+	}
+	sourceIP := masterIP
+	minLag := int64(backupMaxReplicaLag)
+	for _, ip := range redisesIP {
+		if ip == masterIP {
+			continue
+		}
+		replica, err := r.RedisClient.GetReplicationInfo(ip, auth)
+		if err != nil {
+			r.Logger.WithValues("namespace", b.Namespace, "name", b.Name).Info("skip replica", "ip", ip, "err", err.Error())
+			continue
+		}
+		if replica.Master || !replica.MasterLinkUp {
+			continue
+		}
+		if lag := master.Offset - replica.Offset; lag <= minLag {
+			sourceIP = ip
+			minLag = lag
+		}
+	}
+
+	for i := range pods.Items {
+		if pods.Items[i].Status.PodIP == sourceIP {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no redis pod found with ip %s", sourceIP)
+}
+
+func (r *RedisBackupHandler) getAuth(rf *middlev1alpha1.RedisFailover) (*util.AuthConfig, error) {
+	auth := &util.AuthConfig{CommandRenames: util.GetRedisCommandRenames(rf), MasterName: util.GetSentinelMasterName(rf)}
+	if err := service.SetRedisPodCommandRenames(r.K8sService, rf, auth); err != nil {
+		return nil, err
+	}
+	if rf.Spec.Auth.SecretPath != "" {
+		secret, err := r.K8sService.GetSecret(rf.Namespace, rf.Spec.Auth.SecretPath)
+		if err != nil {
+			return nil, err
+		}
+		auth.Password = string(secret.Data["password"])
+	}
+	return auth, nil
+}
+
+// setFailed ends the backup as Failed, the failure is recorded rather than returned
+func (r *RedisBackupHandler) setFailed(b *middlev1alpha1.RedisBackup, message string) error {
+	now := metav1.Now()
+	b.Status.Phase = middlev1alpha1.RedisBackupPhaseFailed
+	b.Status.CompletionTime = &now
+	b.Status.Message = message
+	r.Record.Event(b, v1.EventTypeWarning, "BackupFailed", message)
+	return nil
+}
+
+func (r *RedisBackupHandler) getLabels(b *middlev1alpha1.RedisBackup) map[string]string {
+	dynLabels := map[string]string{
+		"redis/v1beta1": fmt.Sprintf("%s%c%s", b.Namespace, '_', b.Spec.RedisFailoverName),
+		"redis/backup":  b.Name,
+	}
+	defaultLabels := map[string]string{
+		"redis/managed-by": "redis-operator",
+	}
+
+	return util.MergeMap(defaultLabels, dynLabels)
+}
+
+func (r *RedisBackupHandler) createOwnerReferences(b *middlev1alpha1.RedisBackup) []metav1.OwnerReference {
+	rcvk := middlev1alpha1.GroupVersion.WithKind("RedisBackup")
+	return []metav1.OwnerReference{
+		*metav1.NewControllerRef(b, rcvk),
+	}
+}
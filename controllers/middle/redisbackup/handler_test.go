@@ -0,0 +1,64 @@
+package redisbackup
+
+import (
+	"reflect"
+	"testing"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+)
+
+func TestSetArtifactMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    middlev1alpha1.RedisBackupStatus
+		wantErr bool
+	}{
+		{
+			name:    "all fields",
+			message: "size=1024\nrdbVersion=0009\nredisVersion=5.0.4\nkeys=42\n",
+			want:    middlev1alpha1.RedisBackupStatus{Size: 1024, RDBVersion: 9, RedisVersion: "5.0.4", Keys: 42},
+		},
+		{
+			name:    "surrounding spaces and unknown lines",
+			message: "  size=10  \nnoise\nrdbVersion=0010\nchecked=yes\nkeys=0",
+			want:    middlev1alpha1.RedisBackupStatus{Size: 10, RDBVersion: 10, Keys: 0},
+		},
+		{
+			name:    "value holding an equal sign",
+			message: "size=1\nrdbVersion=9\nredisVersion=a=b\nkeys=1",
+			want:    middlev1alpha1.RedisBackupStatus{Size: 1, RDBVersion: 9, RedisVersion: "a=b", Keys: 1},
+		},
+		{
+			name:    "missing size",
+			message: "rdbVersion=9\nkeys=1",
+			wantErr: true,
+		},
+		{
+			name:    "malformed rdb version",
+			message: "size=1\nrdbVersion=REDI\nkeys=1",
+			wantErr: true,
+		},
+		{
+			name:    "missing key count",
+			message: "size=1\nrdbVersion=9",
+			wantErr: true,
+		},
+		{
+			name:    "empty message",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &middlev1alpha1.RedisBackup{}
+			err := setArtifactMetadata(b, tt.message)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setArtifactMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(b.Status, tt.want) {
+				t.Errorf("setArtifactMetadata() status = %+v, want %+v", b.Status, tt.want)
+			}
+		})
+	}
+}
@@ -18,36 +18,97 @@ package middle
 
 import (
 	"context"
+	"time"
 
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/backupservice"
+	"github.com/DevineLiu/redis-operator/controllers/middle/client/k8s"
+	"github.com/DevineLiu/redis-operator/controllers/middle/client/redis"
+	"github.com/DevineLiu/redis-operator/controllers/middle/redisbackup"
+	"github.com/DevineLiu/redis-operator/controllers/middle/service"
+	"github.com/go-logr/logr"
 )
 
+// BackupPollTime is the interval to poll a backup which is still pending or running
+const BackupPollTime = 10
+
 // RedisBackupReconciler reconciles a RedisBackup object
 type RedisBackupReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme  *runtime.Scheme
+	Logger  logr.Logger
+	Record  record.EventRecorder
+	Handler *redisbackup.RedisBackupHandler
 }
 
 //+kubebuilder:rbac:groups=middle.alauda.cn,resources=redisbackups,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=middle.alauda.cn,resources=redisbackups/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=middle.alauda.cn,resources=redisbackups/finalizers,verbs=update
 
-func (r *RedisBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *RedisBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	_ = log.FromContext(ctx)
+	instance := &middlev1alpha1.RedisBackup{}
+	err = r.Client.Get(ctx, req.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	// the handler only changes the status in memory, it is written once the reconcile is done
+	status := newStatusPatcher(r.Client, instance)
+	defer func() {
+		if patchErr := status.Patch(ctx, instance); patchErr != nil && err == nil {
+			err = patchErr
+		}
+	}()
 
-	// your logic here
-
-	return ctrl.Result{}, nil
+	if err = r.Handler.Do(instance); err != nil {
+		return reconcile.Result{}, err
+	}
+	if instance.Status.IsFinished() {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: time.Duration(BackupPollTime) * time.Second}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *RedisBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.SetupEventRecord(mgr)
+	r.SetupHandler(mgr)
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&middlev1alpha1.RedisBackup{}).
+		Owns(&batchv1.Job{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
 		Complete(r)
 }
+
+// SetupEventRecord setup event  record for controller
+func (r *RedisBackupReconciler) SetupEventRecord(mgr ctrl.Manager) {
+	r.Record = mgr.GetEventRecorderFor("redis-backup")
+}
+
+func (r *RedisBackupReconciler) SetupHandler(mgr ctrl.Manager) {
+	k8sService := k8s.New(mgr.GetClient(), r.Logger)
+	redisClient := redis.New()
+	rbkc := backupservice.NewRedisBackupKubeClient(k8sService, r.Logger, r.Client.Status(), r.Record)
+	rfChecker := service.NewRedisFailoverChecker(k8sService, r.Logger, r.Client.Status(), r.Record, redisClient)
+	r.Handler = &redisbackup.RedisBackupHandler{
+		Logger:      r.Logger,
+		Record:      r.Record,
+		K8sService:  k8sService,
+		RbServices:  rbkc,
+		RfChecker:   rfChecker,
+		RedisClient: redisClient,
+	}
+}
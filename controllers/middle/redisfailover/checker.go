@@ -1,183 +1,268 @@
 package redisfailover
 
 import (
-	"context"
-	"errors"
 	"fmt"
 	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/service"
 	util2 "github.com/DevineLiu/redis-operator/controllers/util"
 	v1 "k8s.io/api/core/v1"
+	"reflect"
 	"time"
 )
 
 func (r *RedisFailoverHandler) CheckAndHeal(rf *middlev1alpha1.RedisFailover) error {
+	if err := r.ReplaceUnreadyRedisPods(rf); err != nil {
+		rf.Status.SetFailedCondition(err.Error())
+		return err
+	}
 	if err := r.RfChecker.CheckRedisNumber(rf); err != nil {
 		r.Record.Event(rf, v1.EventTypeNormal, "WaitPodReady", "waiting for all redis pods ready")
 		r.Logger.WithValues("namespace", rf.Namespace, "name", rf.Name).V(2).Info("waiting all redis instance ready")
 		rf.Status.SetWaitingPodReadyCondition("waiting pod ready")
-		if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-			return err
-		}
+		return err
+	}
+
+	if err := r.RfChecker.CheckSharedSentinels(rf); err != nil {
+		rf.Status.SetFailedCondition(err.Error())
+		r.Record.Event(rf, v1.EventTypeWarning, "SharedSentinels", err.Error())
 		return err
 	}
 
 	if err := r.RfChecker.CheckSentinelNumber(rf); err != nil {
 		rf.Status.SetFailedCondition(err.Error())
-		if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-			return err
-		}
 		r.Record.Event(rf, v1.EventTypeWarning, "Error", err.Error())
 		return nil
 	}
-	auth := util2.AuthConfig{}
-	if rf.Spec.Auth.SecretPath != "" {
-		secret, err := r.K8sService.GetSecret(rf.Namespace, rf.Spec.Auth.SecretPath)
+	auth, err := r.getAuth(rf)
+	if err != nil {
+		return err
+	}
 
+	restoring := service.IsRedisRestorePending(rf)
+	if restoring {
+		staged, err := r.RfChecker.IsRedisRestoreStaged(rf)
 		if err != nil {
+			rf.Status.SetFailedCondition(err.Error())
 			return err
 		}
-		passwd := string(secret.Data["password"])
-		auth = util2.AuthConfig{Password: passwd}
+		if !staged {
+			// the instance existed before restore.backupName was set
+			message := fmt.Sprintf("instance already created, %s is not restored", service.DescribeRedisRestoreSource(rf))
+			r.Record.Event(rf, v1.EventTypeWarning, "RestoreSkipped", message)
+			rf.Status.SetRestoreSkippedCondition(message)
+			restoring = false
+		}
 	}
 
-	nMasters, err := r.RfChecker.GetNumberMasters(rf, &auth)
+	nMasters, err := r.RfChecker.GetNumberMasters(rf, auth)
 	if err != nil {
 		rf.Status.SetFailedCondition(err.Error())
-		if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-			return err
-		}
 		return err
 
 	}
 	switch nMasters {
 	case 0:
-		redisesIP, err := r.RfChecker.GetRedisesIPs(rf, &auth)
-		if err != nil {
-			rf.Status.SetFailedCondition(err.Error())
-			if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
+		if restoring {
+			if err := r.RfHealer.SetFirstAsMaster(rf, auth); err != nil {
+				rf.Status.SetFailedCondition(err.Error())
 				return err
 			}
+			break
+		}
+		redisesIP, err := r.RfChecker.GetRedisesIPs(rf, auth)
+		if err != nil {
+			rf.Status.SetFailedCondition(err.Error())
 			return err
 		}
 		if len(redisesIP) == 1 {
-			if err := r.RfHealer.MakeMaster(redisesIP[0], &auth); err != nil {
+			if err := r.RfHealer.MakeMaster(redisesIP[0], auth); err != nil {
 				rf.Status.SetFailedCondition(err.Error())
-				if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-					return err
-				}
 				return err
 			}
 			break
 		}
 		if _, err := r.RfChecker.GetMinimumRedisPodTime(rf); err != nil {
 			rf.Status.SetFailedCondition(err.Error())
-			if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-				return err
-			}
 			return err
 		}
-		if err := r.RfHealer.SetOldestAsMaster(rf, &auth); err != nil {
+		if err := r.RfHealer.SetMostUpToDateAsMaster(rf, auth); err != nil {
 			rf.Status.SetFailedCondition(err.Error())
-			if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-				return err
-			}
 			return err
 		}
 	case 1:
 		break
 	default:
-		return errors.New("more than one master, fix manually")
+		if err := r.ResolveSplitBrain(rf, auth); err != nil {
+			rf.Status.SetFailedCondition(err.Error())
+			return err
+		}
 	}
-	master, err := r.RfChecker.GetMasterIP(rf, &auth)
+	master, err := r.RfChecker.GetMasterIP(rf, auth)
 	if err != nil {
+		rf.Status.Master.Status = middlev1alpha1.RedisStatusMasterDown
+		rf.Status.SetUnavailableCondition(err.Error())
+		rf.Status.SetFailedCondition(err.Error())
+		return err
+	}
+	if err := r.setMasterStatus(rf, master, auth); err != nil {
 		rf.Status.SetFailedCondition(err.Error())
-		if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-			return err
-		}
 		return err
 	}
-	if err := r.RfChecker.CheckAllSlavesFromMaster(master, rf, &auth); err != nil {
-		if err := r.RfHealer.SetMasterOnAll(master, rf, &auth); err != nil {
+	endPendingFailover(rf, rf.Status.Master.Name)
+	if err := r.RfChecker.CheckAllSlavesFromMaster(master, rf, auth); err != nil {
+		if err := r.RfHealer.SetMasterOnAll(master, rf, auth); err != nil {
 			rf.Status.SetFailedCondition(err.Error())
-			if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-				return err
-			}
 			return err
 		}
 	}
-	if err = r.setRedisConfig(rf, &auth); err != nil {
-		rf.Status.SetFailedCondition(err.Error())
-		if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-			return err
+	if wait, err := r.ScaleDownRedis(rf, master, auth); err != nil || wait {
+		if err != nil {
+			rf.Status.SetFailedCondition(err.Error())
 		}
 		return err
 	}
+	if restoring {
+		message := fmt.Sprintf("restored from %s", service.DescribeRedisRestoreSource(rf))
+		r.Record.Event(rf, v1.EventTypeNormal, "Restored", message)
+		rf.Status.SetRestoredCondition(message)
+	}
+	if err = r.setRedisConfig(rf, auth); err != nil {
+		rf.Status.SetFailedCondition(err.Error())
+		return err
+	}
 	sentinels, err := r.RfChecker.GetSentinelsIPs(rf)
 	if err != nil {
 		rf.Status.SetFailedCondition(err.Error())
-		if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
+		return err
+	}
+	if rf.Spec.Expose != nil {
+		if err := r.setAnnounceAddresses(rf, auth, sentinels); err != nil {
+			rf.Status.SetFailedCondition(err.Error())
 			return err
 		}
+	}
+	monitor, monitorPort, err := r.RfChecker.GetRedisAddress(rf, master)
+	if err != nil {
+		rf.Status.SetFailedCondition(err.Error())
 		return err
 	}
 	for _, sip := range sentinels {
-		if err := r.RfChecker.CheckSentinelMonitor(sip, master, &auth); err != nil {
-			if err := r.RfHealer.NewSentinelMonitor(sip, master, rf, &auth); err != nil {
+		if err := r.RfChecker.CheckSentinelMonitor(sip, monitor, monitorPort, auth); err != nil {
+			if err := r.RfHealer.NewSentinelMonitor(sip, monitor, monitorPort, rf, auth); err != nil {
 				rf.Status.SetFailedCondition(err.Error())
-				if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-					return err
-				}
 				return err
 			}
 		}
 	}
+	if err := r.setSentinelCommandRenames(rf, auth, sentinels); err != nil {
+		rf.Status.SetFailedCondition(err.Error())
+		return err
+	}
+	if err := r.restoreSentinelSlaves(rf, auth, sentinels); err != nil {
+		rf.Status.SetFailedCondition(err.Error())
+		return err
+	}
 	for _, sip := range sentinels {
-		if err := r.RfChecker.CheckSentinelSlavesNumberInMemory(sip, rf, &auth); err != nil {
-			if err := r.RfHealer.RestoreSentinel(sip, &auth); err != nil {
+		if err := r.RfChecker.CheckSentinelNumberInMemory(sip, rf, auth); err != nil {
+			if err := r.RfHealer.RestoreSentinel(sip, auth); err != nil {
 				rf.Status.SetFailedCondition(err.Error())
-				if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-					return err
-				}
-				return err
-			}
-			if err := r.waitRestoreSentinelSlavesOK(sip, rf, &auth); err != nil {
-				rf.Status.SetFailedCondition(err.Error())
-				if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-					return err
-				}
 				return err
 			}
 		}
 	}
+	if err = r.setSentinelConfig(rf, auth, sentinels); err != nil {
+		rf.Status.SetFailedCondition(err.Error())
+		return err
+	}
+	if wait, err := r.Switchover(rf, master, sentinels, auth); err != nil || wait {
+		if err != nil {
+			rf.Status.SetFailedCondition(err.Error())
+		}
+		return err
+	}
+	if err = r.restoreElectionPriorities(rf, auth); err != nil {
+		rf.Status.SetFailedCondition(err.Error())
+		return err
+	}
+	if err = r.UpgradeRedis(rf, master, sentinels, auth); err != nil {
+		rf.Status.SetFailedCondition(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *RedisFailoverHandler) setSentinelConfig(rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig, sentinels []string) error {
 	for _, sip := range sentinels {
-		if err := r.RfChecker.CheckSentinelNumberInMemory(sip, rf, &auth); err != nil {
-			if err := r.RfHealer.RestoreSentinel(sip, &auth); err != nil {
-				rf.Status.SetFailedCondition(err.Error())
-				if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-					return err
-				}
-				rf.Status.SetFailedCondition(err.Error())
-				if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
-					return err
-				}
+		if err := r.RfChecker.CheckSentinelConfig(sip, rf, auth); err != nil {
+			r.Record.Event(rf, v1.EventTypeWarning, "CheckConfigErr", err.Error())
+			if err := r.RfHealer.SetSentinelCustomConfig(sip, rf, auth); err != nil {
 				return err
 			}
 		}
 	}
-	if err = r.setSentinelConfig(rf, &auth, sentinels); err != nil {
-		rf.Status.SetFailedCondition(err.Error())
-		if err := r.StatusWriter.Status().Update(context.Background(), rf); err != nil {
+	return nil
+}
+
+// setSentinelCommandRenames tells the sentinels the renames they send the commands of a failover with when they
+// changed since the last time, the renames dropped meanwhile are reverted
+func (r *RedisFailoverHandler) setSentinelCommandRenames(rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig, sentinels []string) error {
+	applied := rf.Status.SentinelCommandRenames
+	if len(applied) == 0 && len(auth.SentinelCommandRenames) == 0 || reflect.DeepEqual(applied, auth.SentinelCommandRenames) {
+		return nil
+	}
+	renames := map[string]string{}
+	for from := range applied {
+		renames[from] = from
+	}
+	for from, to := range auth.SentinelCommandRenames {
+		renames[from] = to
+	}
+	for _, sip := range sentinels {
+		if err := r.RfHealer.SetSentinelCommandRenames(sip, renames, auth); err != nil {
 			return err
 		}
-		return err
 	}
-
+	rf.Status.SentinelCommandRenames = auth.SentinelCommandRenames
 	return nil
 }
 
-func (r *RedisFailoverHandler) setSentinelConfig(rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig, sentinels []string) error {
+// setAnnounceAddresses has the redis pods and the sentinels announce the address of the Service exposing them
+func (r *RedisFailoverHandler) setAnnounceAddresses(rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig, sentinels []string) error {
+	redises, err := r.RfChecker.GetRedisesIPs(rf, auth)
+	if err != nil {
+		return err
+	}
+	for _, rip := range redises {
+		if err := r.RfHealer.SetRedisAnnounceAddress(rip, rf, auth); err != nil {
+			return err
+		}
+	}
+	// shared sentinels announce the address the RedisFailover running them exposes
+	if rf.Spec.Sentinel.Shared != "" {
+		return nil
+	}
+	if len(sentinels) == 0 {
+		return nil
+	}
+	// sentinels older than 6.2 cannot change their announced address at runtime. Validate rejects the images
+	// tagged with such a version, the version of the others is reported once rather than failing on every
+	// sentinel of every reconcile.
+	version, err := r.RfChecker.GetSentinelVersion(sentinels[0], auth)
+	if err != nil {
+		return err
+	}
+	previous := rf.Status.SentinelVersion
+	rf.Status.SentinelVersion = version
+	if !middlev1alpha1.CanSentinelAnnounce(version) {
+		if version != previous {
+			r.Record.Event(rf, v1.EventTypeWarning, "SentinelAnnounceUnsupported",
+				fmt.Sprintf("sentinels of redis %s announce their pod IP, announcing the exposed address requires redis 6.2", version))
+		}
+		return nil
+	}
 	for _, sip := range sentinels {
-		if err := r.RfHealer.SetSentinelCustomConfig(sip, rf, auth); err != nil {
+		if err := r.RfHealer.SetSentinelAnnounceAddress(sip, rf, auth); err != nil {
 			return err
 		}
 	}
@@ -200,6 +285,25 @@ func (r *RedisFailoverHandler) setRedisConfig(rf *middlev1alpha1.RedisFailover,
 	return nil
 }
 
+// restoreSentinelSlaves resets the sentinels that don't list every replica. The sentinels don't list the replicas
+// at a slave-priority of 0, nothing is reset while excludeFromElection keeps replicas out of a failover.
+func (r *RedisFailoverHandler) restoreSentinelSlaves(rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig, sentinels []string) error {
+	if len(rf.Status.ExcludedReplicas) > 0 {
+		return nil
+	}
+	for _, sip := range sentinels {
+		if err := r.RfChecker.CheckSentinelSlavesNumberInMemory(sip, rf, auth); err != nil {
+			if err := r.RfHealer.RestoreSentinel(sip, auth); err != nil {
+				return err
+			}
+			if err := r.waitRestoreSentinelSlavesOK(sip, rf, auth); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (r *RedisFailoverHandler) waitRestoreSentinelSlavesOK(sentinel string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
 	timer := time.NewTimer(30 * time.Second)
 	defer timer.Stop()
@@ -9,19 +9,26 @@ func (r *RedisFailoverHandler) Ensure(rf *middlev1alpha1.RedisFailover, labels m
 	if err := r.RfServices.EnsureRedisService(rf, labels, own); err != nil {
 		return err
 	}
-	if err := r.RfServices.EnsureSentinelService(rf, labels, own); err != nil {
-		return err
-	}
-	if err := r.RfServices.EnsureSentinelHeadlessService(rf, labels, own); err != nil {
-		return err
-	}
-	if err := r.RfServices.EnsureSentinelConfigMap(rf, labels, own); err != nil {
-		return err
+	// shared sentinels are run by the RedisFailover of Sentinel.Shared
+	sentinels := rf.Spec.Sentinel.Shared == ""
+	if sentinels {
+		if err := r.RfServices.EnsureSentinelService(rf, labels, own); err != nil {
+			return err
+		}
+		if err := r.RfServices.EnsureSentinelHeadlessService(rf, labels, own); err != nil {
+			return err
+		}
+		if err := r.RfServices.EnsureSentinelConfigMap(rf, labels, own); err != nil {
+			return err
+		}
+		if err := r.RfServices.EnsureSentinelProbeConfigMap(rf, labels, own); err != nil {
+			return err
+		}
 	}
-	if err := r.RfServices.EnsureSentinelProbeConfigMap(rf, labels, own); err != nil {
+	if err := r.RfServices.EnsureRedisShutdownConfigMap(rf, labels, own); err != nil {
 		return err
 	}
-	if err := r.RfServices.EnsureRedisShutdownConfigMap(rf, labels, own); err != nil {
+	if err := r.RfServices.EnsureRedisConfigMap(rf, labels, own); err != nil {
 		return err
 	}
 
@@ -31,11 +38,22 @@ func (r *RedisFailoverHandler) Ensure(rf *middlev1alpha1.RedisFailover, labels m
 		}
 	}
 
-	if err := r.RfServices.EnsureSentinelDeployment(rf, labels, own); err != nil {
+	if sentinels {
+		if err := r.RfServices.EnsureSentinelDeployment(rf, labels, own); err != nil {
+			return err
+		}
+	}
+	if err := r.RfServices.EnsureRedisRestore(rf, labels, own); err != nil {
 		return err
 	}
 	if err := r.RfServices.EnsureRedisStatefulSet(rf, labels, own); err != nil {
 		return err
 	}
+	if err := r.RfServices.EnsureRedisNodePortService(rf, labels, own); err != nil {
+		return err
+	}
+	if err := r.RfServices.EnsureRedisBackupSchedules(rf, labels, own); err != nil {
+		return err
+	}
 	return nil
 }
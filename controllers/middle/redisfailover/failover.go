@@ -0,0 +1,104 @@
+package redisfailover
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/service"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// failoverTimeout is how long the sentinels have to move the master off the pod the operator failed over
+const failoverTimeout = 2 * time.Minute
+
+// failover has a sentinel move the master off the given pod. The failover is asked once and recorded in the
+// status until the master moves, it fails once the sentinels did not move the master within failoverTimeout
+// and is asked again on the next call.
+func (r *RedisFailoverHandler) failover(rf *middlev1alpha1.RedisFailover, masterPod string, sentinels []string, auth *util2.AuthConfig) error {
+	if pending := rf.Status.PendingFailover; pending != nil && pending.From == masterPod {
+		if time.Since(pending.StartTime.Time) < failoverTimeout {
+			return nil
+		}
+		rf.Status.PendingFailover = nil
+		return fmt.Errorf("the sentinels did not move the master off %s within %s", masterPod, failoverTimeout)
+	}
+	// a failover the sentinels are already running is waited for the same way
+	if err := r.RfHealer.SentinelFailover(sentinels[0], auth); err != nil && !strings.HasPrefix(err.Error(), "INPROGRESS") {
+		return err
+	}
+	rf.Status.PendingFailover = &middlev1alpha1.RedisPendingFailover{From: masterPod, StartTime: metav1.Now()}
+	return nil
+}
+
+// endPendingFailover forgets the pending failover once the master moved
+func endPendingFailover(rf *middlev1alpha1.RedisFailover, masterPod string) {
+	if pending := rf.Status.PendingFailover; pending != nil && pending.From != masterPod {
+		rf.Status.PendingFailover = nil
+	}
+}
+
+// excludeFromElection keeps the given redis pods, by name with their IP, from being promoted by a failover with
+// a slave-priority of 0. The pods are first recorded in the status and only excluded by a later call, once the
+// status is written, so restoreElectionPriorities gives them their priority back whatever happens meanwhile.
+// It reports whether the pods are excluded.
+func (r *RedisFailoverHandler) excludeFromElection(rf *middlev1alpha1.RedisFailover, pods map[string]string, auth *util2.AuthConfig) (bool, error) {
+	recorded := map[string]bool{}
+	for _, name := range rf.Status.ExcludedReplicas {
+		recorded[name] = true
+	}
+	excluded := true
+	for name := range pods {
+		if !recorded[name] {
+			rf.Status.ExcludedReplicas = append(rf.Status.ExcludedReplicas, name)
+			excluded = false
+		}
+	}
+	if !excluded {
+		sort.Strings(rf.Status.ExcludedReplicas)
+		return false, nil
+	}
+	for _, ip := range pods {
+		if ip == "" {
+			continue
+		}
+		if err := r.RfHealer.SetReplicaPriority(ip, 0, auth); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// restoreElectionPriorities gives the pods excluded by excludeFromElection the slave-priority of redis.conf
+// back, once no scale-down or switchover needs them excluded
+func (r *RedisFailoverHandler) restoreElectionPriorities(rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
+	if len(rf.Status.ExcludedReplicas) == 0 || rf.Status.IsScalingDown() || rf.Status.IsSwitchingOver() {
+		return nil
+	}
+	priority, err := service.GetRedisReplicaPriority(r.K8sService, rf)
+	if err != nil {
+		return err
+	}
+	pods, err := r.K8sService.GetStatefulSetPods(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		return err
+	}
+	excluded := map[string]bool{}
+	for _, name := range rf.Status.ExcludedReplicas {
+		excluded[name] = true
+	}
+	// the removed pods are gone, the restarted ones start from redis.conf
+	for _, pod := range pods.Items {
+		if !excluded[pod.Name] || pod.Status.PodIP == "" {
+			continue
+		}
+		if err := r.RfHealer.SetReplicaPriority(pod.Status.PodIP, priority, auth); err != nil {
+			return err
+		}
+	}
+	rf.Status.ExcludedReplicas = nil
+	return nil
+}
@@ -1,7 +1,6 @@
 package redisfailover
 
 import (
-	"context"
 	"fmt"
 	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
 	"github.com/DevineLiu/redis-operator/controllers/middle/client/k8s"
@@ -11,7 +10,6 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 
@@ -25,7 +23,6 @@ type RedisFailoverHandler struct {
 	RfServices   service.RedisFailoverClient
 	RfChecker    service.RedisFailoverCheck
 	RfHealer     service.RedisFailoverHeal
-	StatusWriter StatusWriter
 }
 
 func (r *RedisFailoverHandler) Do(rf *middlev1alpha1.RedisFailover) error {
@@ -33,6 +30,16 @@ func (r *RedisFailoverHandler) Do(rf *middlev1alpha1.RedisFailover) error {
 		r.Record.Event(rf, v1.EventTypeWarning, "Valiadte", fmt.Sprintf("err: %s", err.Error()))
 		return err
 	}
+	// paused, nothing is changed on the instance, in maintenance only its Kubernetes objects are
+	mode, by := middlev1alpha1.GetPauseMode(rf)
+	rf.Status.SetPausedCondition(mode, by)
+	if mode == middlev1alpha1.PauseModePaused {
+		if err := r.setInstanceStatus(rf); err != nil {
+			return err
+		}
+		return r.reportMaster(rf)
+	}
+
 	oRefs := r.createOwnerReferences(rf)
 	labels := r.getLabels(rf)
 
@@ -42,20 +49,26 @@ func (r *RedisFailoverHandler) Do(rf *middlev1alpha1.RedisFailover) error {
 	if err := r.Ensure(rf, labels, oRefs); err != nil {
 		r.Record.Event(rf, v1.EventTypeWarning, "EnsureError", err.Error())
 		rf.Status.SetFailedCondition(err.Error())
-		r.StatusWriter.Update(rf)
 		return err
 	}
 
+	if err := r.setInstanceStatus(rf); err != nil {
+		rf.Status.SetFailedCondition(err.Error())
+		return err
+	}
+	if mode == middlev1alpha1.PauseModeMaintenance {
+		return r.reportMaster(rf)
+	}
+
 	r.Logger.WithValues("namespace", rf.Namespace, "name", rf.Name).V(2).Info("CheckAndHeal...")
 	r.Record.Event(rf, v1.EventTypeNormal, "Heal", "CheckAndHeal")
 	if err := r.CheckAndHeal(rf); err != nil {
 		r.Logger.WithValues("namespace", rf.Namespace, "name", rf.Name).V(2).Info("CheckAndHealError: %s", err.Error())
-		if rf.Status.IsLastConditionWaitingPodReady() {
+		if rf.Status.IsWaitingPodReady() {
 			r.Record.Event(rf, v1.EventTypeNormal, "CreateCluster", "CreateCluster for waiting pod ")
 		} else {
 			r.Record.Event(rf, v1.EventTypeWarning, "CheckAndHealError", err.Error())
 			rf.Status.SetFailedCondition(err.Error())
-			r.StatusWriter.Update(rf)
 			return err
 		}
 		return err
@@ -63,10 +76,27 @@ func (r *RedisFailoverHandler) Do(rf *middlev1alpha1.RedisFailover) error {
 	r.Logger.WithValues("namespace", rf.Namespace, "name", rf.Name).V(2).Info("SetReadyCondition...")
 	r.Record.Event(rf, v1.EventTypeNormal, "HEALTH", "Cluster Be Healthly")
 	rf.Status.SetReadyCondition("HEALTHLY")
-	r.StatusWriter.Update(rf)
 	return nil
 }
 
+// getAuth returns how the operator authenticates to redis and the sentinels of the instance
+func (r *RedisFailoverHandler) getAuth(rf *middlev1alpha1.RedisFailover) (*util.AuthConfig, error) {
+	auth := &util.AuthConfig{CommandRenames: util.GetRedisCommandRenames(rf), MasterName: util.GetSentinelMasterName(rf)}
+	auth.SentinelCommandRenames = util.GetSentinelCommandRenames(auth.CommandRenames)
+	// the pods only run new renames once restarted
+	if err := service.SetRedisPodCommandRenames(r.K8sService, rf, auth); err != nil {
+		return nil, err
+	}
+	if rf.Spec.Auth.SecretPath != "" {
+		secret, err := r.K8sService.GetSecret(rf.Namespace, rf.Spec.Auth.SecretPath)
+		if err != nil {
+			return nil, err
+		}
+		auth.Password = string(secret.Data["password"])
+	}
+	return auth, nil
+}
+
 func (r *RedisFailoverHandler) getLabels(rf *middlev1alpha1.RedisFailover) map[string]string {
 	dynLabels := map[string]string{
 		"redis/v1beta1": fmt.Sprintf("%s%c%s", rf.Namespace, '_', rf.Name),
@@ -84,25 +114,3 @@ func (r *RedisFailoverHandler) createOwnerReferences(rf *middlev1alpha1.RedisFai
 		*metav1.NewControllerRef(rf, rcvk),
 	}
 }
-
-type StatusWriter struct {
-	client.Client
-	Ctx context.Context
-}
-
-type StatusWrite interface {
-	Update(rf *middlev1alpha1.RedisFailover,opts ...client.UpdateOption) error
-	Patch(rf *middlev1alpha1.RedisFailover, patch client.Patch, opts ...client.PatchOption) error
-}
-
-
-
-func (s *StatusWriter) Patch(ctx context.Context, rf *middlev1alpha1.RedisFailover, patch client.Patch, opts ...client.PatchOption) error {
-	err := s.Status().Patch(s.Ctx,rf,patch,opts...)
-	return err
-}
-
-func (s *StatusWriter)Update(rf *middlev1alpha1.RedisFailover,opts ...client.UpdateOption) error  {
-	err :=s.Status().Update(s.Ctx,rf,opts...)
-	return err
-}
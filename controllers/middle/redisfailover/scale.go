@@ -0,0 +1,91 @@
+package redisfailover
+
+import (
+	"fmt"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ScaleDownRedis removes the redis pods beyond Spec.Redis.Replicas, which EnsureRedisStatefulSet keeps
+// running until then. When the master is one of them the pods are excluded from the election and the
+// master is moved away with SENTINEL FAILOVER, the StatefulSet is only shrunk once every sentinel
+// monitors a master that stays. The sentinels don't list the excluded replicas meanwhile, so they are
+// only reset once the removed pods stopped, to forget them. The priority of the replicas that stay is
+// restored by restoreElectionPriorities, even when the scale-down is reverted before its end. It reports whether the rest of the check has to wait for the next reconcile.
+func (r *RedisFailoverHandler) ScaleDownRedis(rf *middlev1alpha1.RedisFailover, master string, auth *util2.AuthConfig) (bool, error) {
+	ss, err := r.K8sService.GetStatefulSet(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		return false, err
+	}
+	pods, err := r.K8sService.GetStatefulSetPods(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		return false, err
+	}
+	sentinels, err := r.RfChecker.GetSentinelsIPs(rf)
+	if err != nil {
+		return false, err
+	}
+	desired := rf.Spec.Redis.Replicas
+
+	if *ss.Spec.Replicas <= desired {
+		if !rf.Status.IsScalingDown() {
+			return false, nil
+		}
+		if int32(len(pods.Items)) > *ss.Spec.Replicas {
+			rf.Status.SetScalingDownCondition("RemovingPods", "waiting for the removed redis pods to stop")
+			return true, nil
+		}
+		for _, sip := range sentinels {
+			if err := r.RfHealer.RestoreSentinel(sip, auth); err != nil {
+				return false, err
+			}
+		}
+		message := fmt.Sprintf("scaled down to %d redis pods", desired)
+		r.Record.Event(rf, v1.EventTypeNormal, "ScaledDown", message)
+		rf.Status.SetScaledDownCondition(message)
+		return false, nil
+	}
+
+	removedReplicas := map[string]string{}
+	masterPod := ""
+	for _, pod := range pods.Items {
+		if int32(util2.GetPodOrdinal(pod.Name)) < desired {
+			continue
+		}
+		if pod.Status.PodIP == master {
+			masterPod = pod.Name
+			continue
+		}
+		removedReplicas[pod.Name] = pod.Status.PodIP
+	}
+
+	if masterPod != "" {
+		if len(sentinels) == 0 {
+			rf.Status.SetScalingDownCondition("Failover", "waiting for a sentinel to move the master off the removed pods")
+			return true, nil
+		}
+		rf.Status.SetScalingDownCondition("Failover", fmt.Sprintf("moving the master %s off the removed pods", masterPod))
+		// the replicas being removed must not be elected either
+		if excluded, err := r.excludeFromElection(rf, removedReplicas, auth); err != nil || !excluded {
+			return true, err
+		}
+		if rf.Status.PendingFailover == nil {
+			r.Record.Event(rf, v1.EventTypeNormal, "Failover", fmt.Sprintf("moving the master %s off the removed pods", masterPod))
+		}
+		return true, r.failover(rf, masterPod, sentinels, auth)
+	}
+
+	if err := r.RfChecker.CheckSentinelsAgreeOnMaster(rf, master, sentinels, auth); err != nil {
+		// the sentinel monitors are healed by the rest of the check
+		rf.Status.SetScalingDownCondition("WaitingSentinels", err.Error())
+		return false, nil
+	}
+
+	message := fmt.Sprintf("scaling down from %d to %d redis pods", *ss.Spec.Replicas, desired)
+	rf.Status.SetScalingDownCondition("RemovingPods", message)
+	r.Record.Event(rf, v1.EventTypeNormal, "ScalingDown", message)
+	ss.Spec.Replicas = &desired
+	return true, r.K8sService.UpdateStatefulSet(rf.Namespace, ss)
+}
@@ -0,0 +1,78 @@
+package redisfailover
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/service"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	"github.com/go-logr/logr"
+)
+
+// fakeSentinels is a checker and a healer of sentinels, a sentinel lists every replica once reset.
+// The calls it does not implement panic.
+type fakeSentinels struct {
+	service.RedisFailoverCheck
+	service.RedisFailoverHeal
+	// missingReplicas are the sentinels not listing every replica
+	missingReplicas map[string]bool
+	reset           []string
+}
+
+func (f *fakeSentinels) CheckSentinelSlavesNumberInMemory(sentinel string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
+	if f.missingReplicas[sentinel] {
+		return errors.New("sentinel's slaves in memory mismatch")
+	}
+	return nil
+}
+
+func (f *fakeSentinels) RestoreSentinel(ip string, auth *util2.AuthConfig) error {
+	f.reset = append(f.reset, ip)
+	delete(f.missingReplicas, ip)
+	return nil
+}
+
+func TestRestoreSentinelSlaves(t *testing.T) {
+	sentinels := []string{"10.0.1.1", "10.0.1.2", "10.0.1.3"}
+	tests := []struct {
+		name            string
+		missingReplicas []string
+		excluded        []string
+		wantReset       []string
+	}{
+		{name: "every replica listed"},
+		{name: "replicas missing", missingReplicas: []string{"10.0.1.2"}, wantReset: []string{"10.0.1.2"}},
+		{
+			name:            "replicas excluded by a switchover",
+			missingReplicas: []string{"10.0.1.1", "10.0.1.2", "10.0.1.3"},
+			excluded:        []string{"redis-redis-1", "redis-redis-2"},
+		},
+		{
+			name:            "replicas excluded by a scale-down",
+			missingReplicas: []string{"10.0.1.1", "10.0.1.3"},
+			excluded:        []string{"redis-redis-3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &middlev1alpha1.RedisFailover{}
+			rf.Status.ExcludedReplicas = tt.excluded
+			fake := &fakeSentinels{missingReplicas: map[string]bool{}}
+			for _, sentinel := range tt.missingReplicas {
+				fake.missingReplicas[sentinel] = true
+			}
+			r := &RedisFailoverHandler{Logger: logr.Discard(), RfChecker: fake, RfHealer: fake}
+
+			if err := r.restoreSentinelSlaves(rf, &util2.AuthConfig{}, sentinels); err != nil {
+				t.Fatal(err)
+			}
+			if len(fake.reset) > 0 || len(tt.wantReset) > 0 {
+				if !reflect.DeepEqual(fake.reset, tt.wantReset) {
+					t.Errorf("reset sentinels = %v, want %v", fake.reset, tt.wantReset)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package redisfailover
+
+import (
+	"errors"
+	"fmt"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ResolveSplitBrain keeps a single master when several redis pods act as master. Unless
+// Spec.Redis.SplitBrainPolicy is manual the master is elected from the sentinels, the run IDs and the
+// replication offsets, and the other masters are made its replicas. The writes they took alone are
+// lost when they resync, the offsets discarded are recorded in a SplitBrainResolved event.
+func (r *RedisFailoverHandler) ResolveSplitBrain(rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
+	if rf.Spec.Redis.SplitBrainPolicy == middlev1alpha1.RedisSplitBrainPolicyManual {
+		return errors.New("more than one master, fix manually")
+	}
+	masters, err := r.RfChecker.GetMastersIPs(rf, auth)
+	if err != nil {
+		return err
+	}
+	// a master demoted itself since the masters were counted
+	if len(masters) < 2 {
+		return nil
+	}
+	master, demoted, err := r.RfChecker.GetSplitBrainMaster(rf, masters, auth)
+	if err != nil {
+		return err
+	}
+	if err := r.RfHealer.SetMasterOnAll(master.IP, rf, auth); err != nil {
+		return err
+	}
+	for _, d := range demoted {
+		message := fmt.Sprintf("demoted the master %s to a replica of %s (%d sentinel votes, offset %d), discarding the replication %s",
+			d.IP, master.IP, master.Votes, master.Replication.Offset, d.DiscardedWrites())
+		r.Record.Event(rf, v1.EventTypeWarning, "SplitBrainResolved", message)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package redisfailover
+
+import (
+	"fmt"
+	"net"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+const sentinelServicePort = "26379"
+
+// setInstanceStatus reports the pods of the redis StatefulSet and of the sentinel Deployment,
+// those of the host of Sentinel.Shared when the sentinels are shared
+func (r *RedisFailoverHandler) setInstanceStatus(rf *middlev1alpha1.RedisFailover) error {
+	instance := middlev1alpha1.RedisStatusInstance{}
+	ss, err := r.K8sService.GetStatefulSet(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		instance.Redis.Size = *ss.Spec.Replicas
+		instance.Redis.Ready = ss.Status.ReadyReplicas
+	}
+
+	sentinelName := util2.GetSentinelGroupName(rf)
+	deploy, err := r.K8sService.GetDeployment(rf.Namespace, sentinelName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		instance.Sentinel.Size = *deploy.Spec.Replicas
+		instance.Sentinel.Ready = deploy.Status.ReadyReplicas
+	}
+	svc, err := r.K8sService.GetService(rf.Namespace, sentinelName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		instance.Sentinel.Service = svc.Name
+		instance.Sentinel.ClusterIP = svc.Spec.ClusterIP
+		instance.Sentinel.Port = sentinelServicePort
+	}
+	rf.Status.Instance = instance
+	return nil
+}
+
+// reportMaster reports the master of the instance without healing it, while the operator is paused
+// or in maintenance
+func (r *RedisFailoverHandler) reportMaster(rf *middlev1alpha1.RedisFailover) error {
+	auth, err := r.getAuth(rf)
+	if err != nil {
+		return err
+	}
+	master, err := r.RfChecker.GetMasterIP(rf, auth)
+	if err != nil {
+		rf.Status.Master.Status = middlev1alpha1.RedisStatusMasterDown
+		rf.Status.SetUnavailableCondition(err.Error())
+		return nil
+	}
+	return r.setMasterStatus(rf, master, auth)
+}
+
+// setMasterStatus reports the master found by the check and the redis version it runs
+func (r *RedisFailoverHandler) setMasterStatus(rf *middlev1alpha1.RedisFailover, master string, auth *util2.AuthConfig) error {
+	pods, err := r.K8sService.GetStatefulSetPods(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		return err
+	}
+	name := ""
+	for _, pod := range pods.Items {
+		if pod.Status.PodIP == master {
+			name = pod.Name
+		}
+	}
+	host, port, err := r.RfChecker.GetRedisAddress(rf, master)
+	if err != nil {
+		return err
+	}
+	version, err := r.RfChecker.GetRedisVersion(master, auth)
+	if err != nil {
+		return err
+	}
+	rf.Status.Master = middlev1alpha1.RedisStatusMaster{
+		Name:    name,
+		Status:  middlev1alpha1.RedisStatusMasterOK,
+		Address: net.JoinHostPort(host, port),
+	}
+	rf.Status.Version = version
+	rf.Status.SetAvailableCondition(fmt.Sprintf("master %s at %s", name, rf.Status.Master.Address))
+	return nil
+}
@@ -0,0 +1,142 @@
+package redisfailover
+
+import (
+	"fmt"
+	"time"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// switchoverTimeout is how long the sentinels and the replicas have to converge on the new master
+const switchoverTimeout = 2 * time.Minute
+
+// Switchover runs the switchover requested with the switchover annotation, once per request ID. It waits
+// for the replicas to be in sync and for an upgrade or a scale-down to end, then excludes the replicas
+// other than the target from the election and has a sentinel fail over. The switchover succeeds once the
+// sentinels monitor the new master and every replica follows it, restoreElectionPriorities then restores
+// the priorities. It reports whether the rest of the check has to wait for the next reconcile.
+func (r *RedisFailoverHandler) Switchover(rf *middlev1alpha1.RedisFailover, master string, sentinels []string, auth *util2.AuthConfig) (bool, error) {
+	id, target, ok := middlev1alpha1.GetSwitchoverRequest(rf)
+	if !ok {
+		return false, nil
+	}
+	status := rf.Status.Switchover
+	if status == nil || status.RequestID != id {
+		status = &middlev1alpha1.RedisSwitchoverStatus{
+			RequestID: id,
+			Target:    target,
+			Phase:     middlev1alpha1.RedisSwitchoverPhasePending,
+		}
+		rf.Status.Switchover = status
+	}
+	if status.Phase == middlev1alpha1.RedisSwitchoverPhaseSucceeded || status.Phase == middlev1alpha1.RedisSwitchoverPhaseFailed {
+		return false, nil
+	}
+
+	pods, err := r.K8sService.GetStatefulSetPods(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		return false, err
+	}
+	podIPs := map[string]string{}
+	masterPod := ""
+	for _, pod := range pods.Items {
+		podIPs[pod.Name] = pod.Status.PodIP
+		if pod.Status.PodIP == master {
+			masterPod = pod.Name
+		}
+	}
+
+	if status.Phase == middlev1alpha1.RedisSwitchoverPhaseRunning {
+		return r.checkSwitchover(rf, status, master, masterPod, sentinels, auth)
+	}
+
+	if status.Target != "" {
+		if status.Target == masterPod {
+			r.endSwitchover(rf, status, middlev1alpha1.RedisSwitchoverPhaseSucceeded, masterPod, fmt.Sprintf("%s is already the master", masterPod))
+			return false, nil
+		}
+		if _, ok := podIPs[status.Target]; !ok {
+			r.endSwitchover(rf, status, middlev1alpha1.RedisSwitchoverPhaseFailed, masterPod, fmt.Sprintf("redis pod %s not found", status.Target))
+			return false, nil
+		}
+	}
+	if len(sentinels) == 0 || len(pods.Items) < 2 {
+		r.endSwitchover(rf, status, middlev1alpha1.RedisSwitchoverPhaseFailed, masterPod, "no sentinel or no replica to fail over to")
+		return false, nil
+	}
+	if rf.Status.IsUpgrading() || rf.Status.IsScalingDown() {
+		status.Message = "waiting for the upgrade or the scale-down to end"
+		return false, nil
+	}
+	if err := r.RfChecker.CheckReplicasInSync(master, rf, auth); err != nil {
+		status.Message = err.Error()
+		return false, nil
+	}
+
+	if status.Target != "" {
+		others := map[string]string{}
+		for name, ip := range podIPs {
+			if name != masterPod && name != status.Target {
+				others[name] = ip
+			}
+		}
+		if excluded, err := r.excludeFromElection(rf, others, auth); err != nil || !excluded {
+			status.Message = "excluding the other replicas from the election"
+			return false, err
+		}
+	}
+	now := metav1.Now()
+	status.Phase = middlev1alpha1.RedisSwitchoverPhaseRunning
+	status.From = masterPod
+	status.StartTime = &now
+	status.Message = ""
+	r.Record.Event(rf, v1.EventTypeNormal, "SwitchoverStarted", fmt.Sprintf("switchover %s: moving the master off %s", id, masterPod))
+	if err := r.RfHealer.SentinelFailover(sentinels[0], auth); err != nil {
+		r.endSwitchover(rf, status, middlev1alpha1.RedisSwitchoverPhaseFailed, masterPod, err.Error())
+		return false, nil
+	}
+	return true, nil
+}
+
+// checkSwitchover follows a running switchover until the sentinels and the replicas converge on the new master
+func (r *RedisFailoverHandler) checkSwitchover(rf *middlev1alpha1.RedisFailover, status *middlev1alpha1.RedisSwitchoverStatus, master, masterPod string,
+	sentinels []string, auth *util2.AuthConfig) (bool, error) {
+	waitErr := error(nil)
+	if masterPod == status.From {
+		waitErr = fmt.Errorf("waiting for the sentinels to promote a replica of %s", status.From)
+	} else if err := r.RfChecker.CheckSentinelsAgreeOnMaster(rf, master, sentinels, auth); err != nil {
+		waitErr = err
+	} else if err := r.RfChecker.CheckAllSlavesFromMaster(master, rf, auth); err != nil {
+		waitErr = err
+	}
+	if waitErr != nil && time.Since(status.StartTime.Time) < switchoverTimeout {
+		status.Message = waitErr.Error()
+		return true, nil
+	}
+
+	switch {
+	case waitErr != nil:
+		r.endSwitchover(rf, status, middlev1alpha1.RedisSwitchoverPhaseFailed, masterPod, fmt.Sprintf("timed out after %s: %v", switchoverTimeout, waitErr))
+	case status.Target != "" && masterPod != status.Target:
+		r.endSwitchover(rf, status, middlev1alpha1.RedisSwitchoverPhaseFailed, masterPod, fmt.Sprintf("the master moved to %s instead of %s", masterPod, status.Target))
+	default:
+		r.endSwitchover(rf, status, middlev1alpha1.RedisSwitchoverPhaseSucceeded, masterPod, fmt.Sprintf("the master moved from %s to %s", status.From, masterPod))
+	}
+	return false, nil
+}
+
+func (r *RedisFailoverHandler) endSwitchover(rf *middlev1alpha1.RedisFailover, status *middlev1alpha1.RedisSwitchoverStatus, phase middlev1alpha1.RedisSwitchoverPhase, masterPod, message string) {
+	now := metav1.Now()
+	status.Phase = phase
+	status.Master = masterPod
+	status.Message = message
+	status.CompletionTime = &now
+	if phase == middlev1alpha1.RedisSwitchoverPhaseSucceeded {
+		r.Record.Event(rf, v1.EventTypeNormal, "SwitchoverSucceeded", fmt.Sprintf("switchover %s: %s", status.RequestID, message))
+		return
+	}
+	r.Record.Event(rf, v1.EventTypeWarning, "SwitchoverFailed", fmt.Sprintf("switchover %s: %s", status.RequestID, message))
+}
@@ -0,0 +1,157 @@
+package redisfailover
+
+import (
+	"fmt"
+	"time"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// RedisFailoverFinalizer holds the deletion of a RedisFailover until Teardown is done
+const RedisFailoverFinalizer = "middle.alauda.cn/redisfailover-teardown"
+
+// teardownResyncTimeout is how long after the deletion the teardown waits for the replicas to catch up with the master
+const teardownResyncTimeout = 2 * time.Minute
+
+// Teardown stops the instance in order before it is deleted: the final backup is taken, the replicas catch
+// up with the master, the sentinels are stopped, or stop monitoring the instance when shared, so they do not
+// fail over, the most up-to-date pod is made the master of the others, then redis is stopped and its volumes
+// are kept or deleted following Storage.KeepAfterDeletion. Nothing is done while the instance is paused.
+// It reports whether the teardown is done and the finalizer can be removed.
+func (r *RedisFailoverHandler) Teardown(rf *middlev1alpha1.RedisFailover) (bool, error) {
+	mode, by := middlev1alpha1.GetPauseMode(rf)
+	rf.Status.SetPausedCondition(mode, by)
+	if mode == middlev1alpha1.PauseModePaused {
+		rf.Status.SetTerminatingCondition("Paused", fmt.Sprintf("the teardown waits for %s to be removed", middlev1alpha1.PausedAnnotation))
+		return false, nil
+	}
+	if rf.Spec.Redis.Backup.FinalBackup != nil {
+		backup, err := r.RfServices.EnsureRedisFinalBackup(rf)
+		if err != nil {
+			return false, err
+		}
+		switch backup.Status.Phase {
+		case middlev1alpha1.RedisBackupPhaseSucceeded:
+		case middlev1alpha1.RedisBackupPhaseFailed:
+			message := fmt.Sprintf("final backup %s failed: %s, remove spec.redis.backup.finalBackup to delete the instance without it", backup.Name, backup.Status.Message)
+			r.Record.Event(rf, v1.EventTypeWarning, "FinalBackupFailed", message)
+			rf.Status.SetTerminatingCondition("FinalBackup", message)
+			return false, nil
+		default:
+			rf.Status.SetTerminatingCondition("FinalBackup", fmt.Sprintf("waiting for final backup %s", backup.Name))
+			return false, nil
+		}
+	}
+
+	if synced, err := r.waitReplicasInSync(rf); err != nil || !synced {
+		return false, err
+	}
+	if rf.Spec.Sentinel.Shared != "" {
+		if err := r.removeSentinelMonitor(rf); err != nil {
+			return false, err
+		}
+	}
+	stopped, err := r.RfServices.EnsureSentinelStopped(rf)
+	if err != nil {
+		return false, err
+	}
+	if !stopped {
+		rf.Status.SetTerminatingCondition("StoppingSentinels", "waiting for the sentinels to stop, and for the instances sharing them to be deleted")
+		return false, nil
+	}
+
+	if err := r.demoteMaster(rf); err != nil {
+		// the data of every replica is still on its volume, so the teardown goes on
+		r.Record.Event(rf, v1.EventTypeWarning, "DemotingMaster", err.Error())
+	}
+
+	stopped, err = r.RfServices.EnsureRedisStopped(rf)
+	if err != nil {
+		return false, err
+	}
+	if !stopped {
+		rf.Status.SetTerminatingCondition("StoppingRedis", "waiting for the redis pods to stop")
+		return false, nil
+	}
+
+	if err := r.RfServices.EnsureRedisVolumesReleased(rf); err != nil {
+		return false, err
+	}
+	r.Record.Event(rf, v1.EventTypeNormal, "Terminated", "teardown done")
+	return true, nil
+}
+
+// removeSentinelMonitor has the shared sentinels stop monitoring the instance, the other instances keep them
+func (r *RedisFailoverHandler) removeSentinelMonitor(rf *middlev1alpha1.RedisFailover) error {
+	rf.Status.SetTerminatingCondition("RemovingMonitor", "removing the master from the shared sentinels")
+	sentinels, err := r.RfChecker.GetSentinelsIPs(rf)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	auth := &util2.AuthConfig{MasterName: util2.GetSentinelMasterName(rf)}
+	for _, sip := range sentinels {
+		if err := r.RfHealer.RemoveSentinelMonitor(sip, auth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitReplicasInSync holds the teardown until the replicas caught up with the master, while the sentinels still
+// run. It gives up teardownResyncTimeout after the deletion, the data of every replica being kept on its volume.
+func (r *RedisFailoverHandler) waitReplicasInSync(rf *middlev1alpha1.RedisFailover) (bool, error) {
+	ss, err := r.K8sService.GetStatefulSet(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	if ss.Spec.Replicas != nil && *ss.Spec.Replicas == 0 {
+		return true, nil
+	}
+	auth, err := r.getAuth(rf)
+	if err != nil {
+		return false, err
+	}
+	master, err := r.RfChecker.GetMasterIP(rf, auth)
+	if err == nil {
+		err = r.RfChecker.CheckReplicasInSync(master, rf, auth)
+	}
+	if err == nil {
+		return true, nil
+	}
+	if rf.DeletionTimestamp != nil && time.Since(rf.DeletionTimestamp.Time) > teardownResyncTimeout {
+		r.Record.Event(rf, v1.EventTypeWarning, "WaitingResync", fmt.Sprintf("tearing down with replicas out of sync: %v", err))
+		return true, nil
+	}
+	rf.Status.SetTerminatingCondition("WaitingResync", err.Error())
+	return false, nil
+}
+
+// demoteMaster makes the pod with the highest replication offset the master while the StatefulSet still runs all
+// of its pods, the sentinels being stopped nothing else moves the master meanwhile
+func (r *RedisFailoverHandler) demoteMaster(rf *middlev1alpha1.RedisFailover) error {
+	ss, err := r.K8sService.GetStatefulSet(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if ss.Spec.Replicas != nil && *ss.Spec.Replicas == 0 {
+		return nil
+	}
+	rf.Status.SetTerminatingCondition("DemotingMaster", "making the most up-to-date redis pod the master")
+	auth, err := r.getAuth(rf)
+	if err != nil {
+		return err
+	}
+	return r.RfHealer.SetMostUpToDateAsMaster(rf, auth)
+}
@@ -0,0 +1,117 @@
+package redisfailover
+
+import (
+	"fmt"
+	"sort"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// UpgradeRedis rolls the redis pods out to the current revision of the StatefulSet, which uses the
+// OnDelete strategy. One pod is replaced per call, once every replica is in sync with the master:
+// the outdated replicas first, from the highest ordinal, then the master is moved onto an upgraded
+// replica with SENTINEL FAILOVER and replaced last, as a replica. The step is reported in the
+// Progressing condition.
+func (r *RedisFailoverHandler) UpgradeRedis(rf *middlev1alpha1.RedisFailover, master string, sentinels []string, auth *util2.AuthConfig) error {
+	ss, err := r.K8sService.GetStatefulSet(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		return err
+	}
+	// the StatefulSet controller has not computed the revision of the latest template yet
+	if ss.Status.ObservedGeneration < ss.Generation || ss.Status.UpdateRevision == "" {
+		return nil
+	}
+	pods, err := r.K8sService.GetStatefulSetPods(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		return err
+	}
+	outdated := []v1.Pod{}
+	var masterPod *v1.Pod
+	for i := range pods.Items {
+		pod := pods.Items[i]
+		if pod.Labels[appsv1.ControllerRevisionHashLabelKey] == ss.Status.UpdateRevision {
+			continue
+		}
+		if pod.Status.PodIP == master {
+			masterPod = &pod
+			continue
+		}
+		outdated = append(outdated, pod)
+	}
+	if len(outdated) == 0 && masterPod == nil {
+		if rf.Status.IsUpgrading() {
+			r.Record.Event(rf, v1.EventTypeNormal, "Upgraded", fmt.Sprintf("redis pods upgraded to %s", ss.Status.UpdateRevision))
+			rf.Status.SetUpgradedCondition(fmt.Sprintf("redis pods upgraded to %s", ss.Status.UpdateRevision))
+		}
+		return nil
+	}
+
+	// a replaced pod has to resync before the next one goes down
+	if err := r.RfChecker.CheckReplicasInSync(master, rf, auth); err != nil {
+		rf.Status.SetUpgradingCondition("WaitingResync", err.Error())
+		return nil
+	}
+
+	if len(outdated) > 0 {
+		sort.Slice(outdated, func(i, j int) bool {
+			return util2.GetPodOrdinal(outdated[i].Name) > util2.GetPodOrdinal(outdated[j].Name)
+		})
+		pod := outdated[0]
+		rf.Status.SetUpgradingCondition("UpgradingReplica", fmt.Sprintf("replacing replica %s", pod.Name))
+		r.Record.Event(rf, v1.EventTypeNormal, "UpgradingReplica", fmt.Sprintf("replacing replica %s", pod.Name))
+		return r.K8sService.DeletePod(rf.Namespace, pod.Name)
+	}
+
+	// only the master is left, a single redis has no replica to fail over to
+	if len(pods.Items) == 1 || len(sentinels) == 0 {
+		rf.Status.SetUpgradingCondition("UpgradingMaster", fmt.Sprintf("replacing master %s", masterPod.Name))
+		r.Record.Event(rf, v1.EventTypeNormal, "UpgradingMaster", fmt.Sprintf("replacing master %s", masterPod.Name))
+		return r.K8sService.DeletePod(rf.Namespace, masterPod.Name)
+	}
+	if rf.Status.PendingFailover == nil {
+		r.Record.Event(rf, v1.EventTypeNormal, "Failover", fmt.Sprintf("moving the master off %s", masterPod.Name))
+	}
+	rf.Status.SetUpgradingCondition("Failover", fmt.Sprintf("moving the master off %s", masterPod.Name))
+	// the old master is replaced as a replica once the sentinels have reconfigured it
+	return r.failover(rf, masterPod.Name, sentinels, auth)
+}
+
+// ReplaceUnreadyRedisPods deletes the redis pods that are not ready and run an outdated revision of the
+// StatefulSet. They are replaced by the current template, which also lets a fixed template replace a pod
+// broken by the previous one: UpgradeRedis only runs once every pod is ready.
+func (r *RedisFailoverHandler) ReplaceUnreadyRedisPods(rf *middlev1alpha1.RedisFailover) error {
+	ss, err := r.K8sService.GetStatefulSet(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		return err
+	}
+	if ss.Status.ObservedGeneration < ss.Generation || ss.Status.UpdateRevision == "" {
+		return nil
+	}
+	pods, err := r.K8sService.GetStatefulSetPods(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || isPodReady(&pod) || pod.Labels[appsv1.ControllerRevisionHashLabelKey] == ss.Status.UpdateRevision {
+			continue
+		}
+		r.Record.Event(rf, v1.EventTypeNormal, "ReplacingPod", fmt.Sprintf("replacing redis pod %s, not ready on the outdated revision %s",
+			pod.Name, pod.Labels[appsv1.ControllerRevisionHashLabelKey]))
+		if err := r.K8sService.DeletePod(rf.Namespace, pod.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -33,6 +33,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -52,19 +53,52 @@ type RedisFailoverReconciler struct {
 //+kubebuilder:rbac:groups=middle.alauda.cn,resources=redisfailovers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=middle.alauda.cn,resources=redisfailovers/finalizers,verbs=update
 
-func (r *RedisFailoverReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *RedisFailoverReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	_ = log.FromContext(ctx)
 	instance := &middlev1alpha1.RedisFailover{}
-	err := r.Client.Get(ctx, req.NamespacedName, instance)
+	err = r.Client.Get(ctx, req.NamespacedName, instance)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return reconcile.Result{}, nil
 		}
 		return reconcile.Result{}, err
 	}
+	// the handlers only change the status in memory, it is written once the reconcile is done
+	status := newStatusPatcher(r.Client, instance)
+	defer func() {
+		if patchErr := status.Patch(ctx, instance); patchErr != nil && err == nil {
+			err = patchErr
+		}
+	}()
+
+	if !instance.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(instance, redisfailover.RedisFailoverFinalizer) {
+			return reconcile.Result{}, nil
+		}
+		instance.Status.MigrateConditions()
+		instance.Status.ObservedGeneration = instance.Generation
+		done, err := r.Handler.Teardown(instance)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !done {
+			return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
+		}
+		controllerutil.RemoveFinalizer(instance, redisfailover.RedisFailoverFinalizer)
+		return reconcile.Result{}, r.Client.Update(ctx, instance)
+	}
+	if !controllerutil.ContainsFinalizer(instance, redisfailover.RedisFailoverFinalizer) {
+		controllerutil.AddFinalizer(instance, redisfailover.RedisFailoverFinalizer)
+		if err = r.Client.Update(ctx, instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
 
+	// updating the finalizer reloaded the status, the conditions are migrated on the latest one
+	instance.Status.MigrateConditions()
+	instance.Status.ObservedGeneration = instance.Generation
 	if err = r.Handler.Do(instance); err != nil {
-		if instance.Status.IsLastConditionWaitingPodReady() {
+		if instance.Status.IsWaitingPodReady() {
 			r.Logger.WithValues("namespace", instance.Namespace, "name", instance.Name).V(2).Info("waiting pod ready", err.Error())
 			return reconcile.Result{RequeueAfter: 20 * time.Second}, nil
 		}
@@ -78,6 +112,10 @@ func (r *RedisFailoverReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	}
 
 	r.Logger.V(5).Info(fmt.Sprintf("RedisFailover Spec:\n %+v", instance))
+	// an upgrade replaces one pod per reconcile, a scale-down takes a few steps
+	if instance.Status.IsUpgrading() || instance.Status.IsScalingDown() || instance.Status.IsSwitchingOver() {
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
 
 	return ctrl.Result{RequeueAfter: time.Duration(ReconcileTime) * time.Second}, nil
 }
@@ -106,11 +144,6 @@ func (r *RedisFailoverReconciler) SetupHandler(mgr ctrl.Manager) {
 	rfkc := service.NewRedisFailoverKubeClient(k8sService, r.Logger, r.Client.Status(), r.Record)
 	rfchecker := service.NewRedisFailoverChecker(k8sService, r.Logger, r.Client.Status(), r.Record, redisClient)
 	rfhealer := service.NewRedisFailoverHealer(k8sService, r.Logger, r.Client.Status(), r.Record, redisClient)
-	status := redisfailover.StatusWriter{
-		Client:r.Client,
-		Ctx: context.TODO(),
-	}
-
 	r.Handler = &redisfailover.RedisFailoverHandler{
 		Logger:       r.Logger,
 		Record:       r.Record,
@@ -118,7 +151,6 @@ func (r *RedisFailoverReconciler) SetupHandler(mgr ctrl.Manager) {
 		RfServices:   rfkc,
 		RfChecker:    rfchecker,
 		RfHealer:     rfhealer,
-		StatusWriter: status,
 	}
 
 }
@@ -1,10 +1,8 @@
 package redisproxy
 
 import (
-	"context"
 	"fmt"
 	"github.com/DevineLiu/redis-operator/controllers/middle/proxyservice"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
 	"github.com/DevineLiu/redis-operator/controllers/middle/client/k8s"
@@ -20,7 +18,6 @@ type RedisProxyHandler struct {
 	Record     record.EventRecorder
 	K8sService k8s.Services
 	RpServices proxyservice.RedisProxyClient
-	StatusWriter StatusWriter
 }
 
 func (r *RedisProxyHandler) Do(rp *middlev1alpha1.RedisProxy) error {
@@ -30,6 +27,13 @@ func (r *RedisProxyHandler) Do(rp *middlev1alpha1.RedisProxy) error {
 		return err
 	}
 
+	// the proxy does not heal redis, maintenance leaves it reconciled as usual
+	mode, by := middlev1alpha1.GetPauseMode(rp)
+	rp.Status.SetPausedCondition(mode, by)
+	if mode == middlev1alpha1.PauseModePaused {
+		return nil
+	}
+
 	oRefs := r.createOwnerReferences(rp)
 	labels := r.getLabels(rp)
 	r.Logger.WithValues("namespace", rp.Namespace, "name", rp.Name).V(2).Info("Ensure...")
@@ -58,26 +62,3 @@ func (r *RedisProxyHandler) getLabels(rp *middlev1alpha1.RedisProxy) map[string]
 
 	return util.MergeMap(defaultLabels, dynLabels, rp.Labels)
 }
-
-
-type StatusWriter struct {
-	client.Client
-	Ctx context.Context
-}
-
-type StatusWrite interface {
-	Update(rf *middlev1alpha1.RedisProxy,opts ...client.UpdateOption) error
-	Patch(rf *middlev1alpha1.RedisProxy, patch client.Patch, opts ...client.PatchOption) error
-}
-
-
-
-func (s *StatusWriter) Patch( rf *middlev1alpha1.RedisProxy, patch client.Patch, opts ...client.PatchOption) error {
-	err := s.Status().Patch(s.Ctx,rf,patch,opts...)
-	return err
-}
-
-func (s *StatusWriter)Update(rf *middlev1alpha1.RedisProxy,opts ...client.UpdateOption) error  {
-	err :=s.Status().Update(s.Ctx,rf,opts...)
-	return err
-}
\ No newline at end of file
@@ -51,16 +51,24 @@ type RedisProxyReconciler struct {
 //+kubebuilder:rbac:groups=middle.alauda.cn,resources=redisproxies/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=middle.alauda.cn,resources=redisproxies/finalizers,verbs=update
 
-func (r *RedisProxyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *RedisProxyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	_ = log.FromContext(ctx)
 	instance := &middlev1alpha1.RedisProxy{}
-	err := r.Client.Get(ctx, req.NamespacedName, instance)
+	err = r.Client.Get(ctx, req.NamespacedName, instance)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return reconcile.Result{}, nil
 		}
 		return reconcile.Result{}, err
 	}
+	status := newStatusPatcher(r.Client, instance)
+	defer func() {
+		if patchErr := status.Patch(ctx, instance); patchErr != nil && err == nil {
+			err = patchErr
+		}
+	}()
+	instance.Status.MigrateConditions()
+	instance.Status.ObservedGeneration = instance.Generation
 	if err = r.Handler.Do(instance); err != nil {
 		return reconcile.Result{}, err
 	}
@@ -87,15 +95,10 @@ func (r *RedisProxyReconciler) SetupHandler(mgr ctrl.Manager) {
 	k8sService := k8s.New(mgr.GetClient(), r.Logger)
 	//redisClient := redis.New()
 	rpkc := proxyservice.NewRedisProxyKubeClient(k8sService, r.Logger, r.Client.Status(), r.Record)
-	status := redisproxy.StatusWriter{
-		Client:r.Client,
-		Ctx: context.TODO(),
-	}
 	r.Handler = &redisproxy.RedisProxyHandler{
 		Logger:     r.Logger,
 		Record:     r.Record,
 		K8sService: k8sService,
 		RpServices: rpkc,
-		StatusWriter: status,
 	}
 }
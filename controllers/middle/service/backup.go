@@ -0,0 +1,158 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	BackupScheduledAtAnnotation = "middle.alauda.cn/scheduled-at"
+	BackupRedisFailoverLabel    = "redis/redisfailover"
+	BackupScheduleLabel         = "redis/backup-schedule"
+)
+
+// backupStartingDeadline is how late a scheduled backup is still taken, like the startingDeadlineSeconds
+// of a CronJob. The ticks missed before it are skipped rather than walked since the last backup.
+const backupStartingDeadline = 24 * time.Hour
+
+// EnsureRedisBackupSchedules creates the RedisBackup due for every schedule of the instance and
+// prunes the backups of each schedule down to its Keep most recent successful ones
+func (r RedisFailoverKubeClient) EnsureRedisBackupSchedules(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
+	for _, schedule := range rf.Spec.Redis.Backup.Schedule {
+		if err := r.ensureRedisBackupSchedule(rf, schedule, ownerRefs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r RedisFailoverKubeClient) ensureRedisBackupSchedule(rf *middlev1alpha1.RedisFailover, schedule middlev1alpha1.Schedule, ownerRefs []metav1.OwnerReference) error {
+	sched, err := cron.ParseStandard(schedule.Schedule)
+	if err != nil {
+		return fmt.Errorf("backup schedule %s: %v", schedule.Name, err)
+	}
+	backupLabels := generateBackupScheduleLabels(rf, schedule.Name)
+	backups, err := r.K8SService.ListRedisBackups(rf.Namespace, backupLabels)
+	if err != nil {
+		return err
+	}
+	items := backups.Items
+	// newest first
+	sort.Slice(items, func(i, j int) bool {
+		return getBackupScheduledAt(&items[i]).After(getBackupScheduledAt(&items[j]))
+	})
+
+	// with KeepAfterDeletion the backups carry no owner reference and survive the RedisFailover
+	backupOwnerRefs := ownerRefs
+	if schedule.KeepAfterDeletion {
+		backupOwnerRefs = nil
+	}
+	for i := range items {
+		if !util2.OwnerReferencesEqual(items[i].OwnerReferences, backupOwnerRefs) {
+			items[i].OwnerReferences = backupOwnerRefs
+			if err := r.K8SService.UpdateRedisBackup(rf.Namespace, &items[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := r.pruneRedisBackups(rf, items, schedule.Keep); err != nil {
+		return err
+	}
+
+	last := rf.CreationTimestamp.Time
+	if len(items) > 0 {
+		last = getBackupScheduledAt(&items[0])
+		// do not stack backups while the previous one is still running
+		if !items[0].Status.IsFinished() {
+			return nil
+		}
+	}
+	scheduledAt, due := getLatestScheduleTime(sched, last, time.Now())
+	if !due {
+		return nil
+	}
+
+	backup := &middlev1alpha1.RedisBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            util2.GetScheduledBackupName(rf, schedule.Name, scheduledAt),
+			Namespace:       rf.Namespace,
+			Labels:          backupLabels,
+			OwnerReferences: backupOwnerRefs,
+			Annotations: map[string]string{
+				BackupScheduledAtAnnotation: scheduledAt.UTC().Format(time.RFC3339),
+			},
+		},
+		Spec: middlev1alpha1.RedisBackupSpec{
+			RedisFailoverName: rf.Name,
+			Image:             rf.Spec.Redis.Backup.Image,
+			Storage:           schedule.Storage,
+			Encryption:        rf.Spec.Redis.Backup.Encryption,
+		},
+	}
+	if err := r.K8SService.CreateRedisBackup(rf.Namespace, backup); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// pruneRedisBackups deletes successful backups beyond keep, and failed backups older than the
+// newest successful one. Backups still in progress are never touched.
+func (r RedisFailoverKubeClient) pruneRedisBackups(rf *middlev1alpha1.RedisFailover, newestFirst []middlev1alpha1.RedisBackup, keep int32) error {
+	succeeded := int32(0)
+	for i := range newestFirst {
+		backup := &newestFirst[i]
+		prune := false
+		switch backup.Status.Phase {
+		case middlev1alpha1.RedisBackupPhaseSucceeded:
+			succeeded++
+			prune = succeeded > keep
+		case middlev1alpha1.RedisBackupPhaseFailed:
+			prune = succeeded > 0
+		}
+		if !prune {
+			continue
+		}
+		if err := r.K8SService.DeleteRedisBackup(rf.Namespace, backup.Name); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// getLatestScheduleTime returns the most recent tick of sched in (last, now], if any, ignoring the ticks
+// older than backupStartingDeadline
+func getLatestScheduleTime(sched cron.Schedule, last, now time.Time) (time.Time, bool) {
+	if earliest := now.Add(-backupStartingDeadline); last.Before(earliest) {
+		last = earliest
+	}
+	var latest time.Time
+	due := false
+	for t := sched.Next(last); !t.IsZero() && !t.After(now); t = sched.Next(t) {
+		latest = t
+		due = true
+	}
+	return latest, due
+}
+
+func getBackupScheduledAt(backup *middlev1alpha1.RedisBackup) time.Time {
+	if t, err := time.Parse(time.RFC3339, backup.Annotations[BackupScheduledAtAnnotation]); err == nil {
+		return t
+	}
+	return backup.CreationTimestamp.Time
+}
+
+func generateBackupScheduleLabels(rf *middlev1alpha1.RedisFailover, schedule string) map[string]string {
+	return map[string]string{
+		"redis/managed-by":       "redis-operator",
+		BackupRedisFailoverLabel: rf.Name,
+		BackupScheduleLabel:      schedule,
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/client/k8s"
+	"github.com/go-logr/logr"
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetLatestScheduleTime(t *testing.T) {
+	hourly, err := cron.ParseStandard("0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weekly, err := cron.ParseStandard("0 0 * * 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2021, 7, 1, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name   string
+		weekly bool
+		last   time.Time
+		now    time.Time
+		want   time.Time
+		wantOK bool
+	}{
+		{name: "no tick since the last backup", last: at(10, 0), now: at(10, 59)},
+		{name: "one tick", last: at(10, 0), now: at(11, 30), want: at(11, 0), wantOK: true},
+		{name: "tick at now is due", last: at(10, 0), now: at(11, 0), want: at(11, 0), wantOK: true},
+		{name: "missed ticks run once at the latest", last: at(10, 0), now: at(13, 15), want: at(13, 0), wantOK: true},
+		{name: "last between ticks", last: at(10, 30), now: at(11, 5), want: at(11, 0), wantOK: true},
+		{name: "last long ago", last: at(10, 0).AddDate(-1, 0, 0), now: at(13, 15), want: at(13, 0), wantOK: true},
+		// 2021-07-01 is a Thursday
+		{name: "tick missed beyond the deadline", weekly: true, last: at(0, 0).AddDate(0, 0, -30), now: at(13, 15)},
+		{name: "tick within the deadline", weekly: true, last: at(0, 0).AddDate(0, 0, -30), now: at(0, 0).AddDate(0, 0, -4).Add(time.Hour), want: at(0, 0).AddDate(0, 0, -4), wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched := hourly
+			if tt.weekly {
+				sched = weekly
+			}
+			got, ok := getLatestScheduleTime(sched, tt.last, tt.now)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("getLatestScheduleTime() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGetBackupScheduledAt(t *testing.T) {
+	created := time.Date(2021, 7, 1, 10, 0, 5, 0, time.UTC)
+	tests := []struct {
+		name       string
+		annotation string
+		want       time.Time
+	}{
+		{name: "annotated", annotation: "2021-07-01T10:00:00Z", want: time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)},
+		{name: "not annotated", want: created},
+		{name: "malformed annotation", annotation: "yesterday", want: created},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backup := &middlev1alpha1.RedisBackup{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)}}
+			if tt.annotation != "" {
+				backup.Annotations = map[string]string{BackupScheduledAtAnnotation: tt.annotation}
+			}
+			if got := getBackupScheduledAt(backup); !got.Equal(tt.want) {
+				t.Errorf("getBackupScheduledAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneRedisBackups(t *testing.T) {
+	const (
+		succeeded = middlev1alpha1.RedisBackupPhaseSucceeded
+		failed    = middlev1alpha1.RedisBackupPhaseFailed
+		running   = middlev1alpha1.RedisBackupPhaseRunning
+	)
+	tests := []struct {
+		name string
+		// phases of the backups, newest first, named backup-0, backup-1...
+		phases []middlev1alpha1.RedisBackupPhase
+		keep   int32
+		want   []string
+	}{
+		{
+			name:   "nothing beyond keep",
+			phases: []middlev1alpha1.RedisBackupPhase{succeeded, succeeded},
+			keep:   2,
+			want:   []string{"backup-0", "backup-1"},
+		},
+		{
+			name:   "oldest successful backups beyond keep",
+			phases: []middlev1alpha1.RedisBackupPhase{succeeded, succeeded, succeeded, succeeded},
+			keep:   2,
+			want:   []string{"backup-0", "backup-1"},
+		},
+		{
+			name:   "failed backups older than a successful one",
+			phases: []middlev1alpha1.RedisBackupPhase{failed, succeeded, failed, succeeded},
+			keep:   3,
+			want:   []string{"backup-0", "backup-1", "backup-3"},
+		},
+		{
+			name:   "failed backups kept while none succeeded",
+			phases: []middlev1alpha1.RedisBackupPhase{failed, failed},
+			keep:   1,
+			want:   []string{"backup-0", "backup-1"},
+		},
+		{
+			name:   "backups in progress never pruned",
+			phases: []middlev1alpha1.RedisBackupPhase{running, succeeded, running, succeeded},
+			keep:   1,
+			want:   []string{"backup-0", "backup-1", "backup-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &middlev1alpha1.RedisFailover{ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default"}}
+			backups := make([]middlev1alpha1.RedisBackup, len(tt.phases))
+			objs := make([]runtime.Object, len(tt.phases))
+			for i, phase := range tt.phases {
+				backups[i] = middlev1alpha1.RedisBackup{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("backup-%d", i), Namespace: rf.Namespace},
+					Status:     middlev1alpha1.RedisBackupStatus{Phase: phase},
+				}
+				objs[i] = backups[i].DeepCopy()
+			}
+			k8sService := newFakeServices(t, objs...)
+			r := RedisFailoverKubeClient{K8SService: k8sService, Logger: logr.Discard()}
+
+			if err := r.pruneRedisBackups(rf, backups, tt.keep); err != nil {
+				t.Fatal(err)
+			}
+			list, err := k8sService.ListRedisBackups(rf.Namespace, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, backup := range list.Items {
+				got = append(got, backup.Name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("remaining backups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeServices is k8s.New on a fake client, without the NameSpaces client that needs a kubeconfig
+type fakeServices struct {
+	k8s.ConfigMap
+	k8s.Pod
+	k8s.PodDisruptionBudget
+	k8s.Service
+	k8s.NameSpaces
+	k8s.Deployment
+	k8s.StatefulSet
+	k8s.Secret
+	k8s.Job
+	k8s.PersistentVolumeClaim
+	k8s.RedisFailover
+	k8s.RedisBackup
+}
+
+func newFakeServices(t *testing.T, objs ...runtime.Object) k8s.Services {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := middlev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
+	logger := logr.Discard()
+	return &fakeServices{
+		ConfigMap:             k8s.NewConfigMap(c, logger),
+		Pod:                   k8s.NewPod(c, logger),
+		PodDisruptionBudget:   k8s.NewPodDisruptionBudget(c, logger),
+		Service:               k8s.NewService(c, logger),
+		Deployment:            k8s.NewDeployment(c, logger),
+		StatefulSet:           k8s.NewStatefulSet(c, logger),
+		Secret:                k8s.NewSecret(c, logger),
+		Job:                   k8s.NewJob(c, logger),
+		PersistentVolumeClaim: k8s.NewPersistentVolumeClaim(c, logger),
+		RedisFailover:         k8s.NewRedisFailover(c, logger),
+		RedisBackup:           k8s.NewRedisBackup(c, logger),
+	}
+}
@@ -17,20 +17,33 @@ import (
 	"time"
 )
 
+// maxReplicaLag is the replication lag in bytes up to which a replica is in sync with its master
+const maxReplicaLag = 1 << 20
+
 type RedisFailoverCheck interface {
 	CheckRedisNumber(rf *v1alpha1.RedisFailover) error
 	CheckSentinelNumber(rf *v1alpha1.RedisFailover) error
 	CheckSentinelReadyReplicas(rf *v1alpha1.RedisFailover) error
+	CheckSharedSentinels(rf *v1alpha1.RedisFailover) error
 	CheckAllSlavesFromMaster(master string, rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) error
 	CheckSentinelNumberInMemory(sentinel string, rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) error
 	CheckSentinelSlavesNumberInMemory(sentinel string, rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) error
-	CheckSentinelMonitor(sentinel string, monitor string, auth *util2.AuthConfig) error
+	CheckSentinelMonitor(sentinel string, monitor string, monitorPort string, auth *util2.AuthConfig) error
+	CheckSentinelConfig(sentinel string, rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) error
 	GetMasterIP(rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) (string, error)
 	GetNumberMasters(rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) (int, error)
+	GetMastersIPs(rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) ([]string, error)
+	GetSplitBrainMaster(rf *v1alpha1.RedisFailover, masters []string, auth *util2.AuthConfig) (*MasterCandidate, []MasterCandidate, error)
 	GetRedisesIPs(rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) ([]string, error)
 	GetSentinelsIPs(rf *v1alpha1.RedisFailover) ([]string, error)
 	GetMinimumRedisPodTime(rf *v1alpha1.RedisFailover) (time.Duration, error)
 	CheckRedisConfig(rf *v1alpha1.RedisFailover, addr string, auth *util2.AuthConfig) error
+	IsRedisRestoreStaged(rf *v1alpha1.RedisFailover) (bool, error)
+	CheckReplicasInSync(master string, rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) error
+	CheckSentinelsAgreeOnMaster(rf *v1alpha1.RedisFailover, master string, sentinels []string, auth *util2.AuthConfig) error
+	GetRedisAddress(rf *v1alpha1.RedisFailover, ip string) (string, string, error)
+	GetRedisVersion(ip string, auth *util2.AuthConfig) (string, error)
+	GetSentinelVersion(ip string, auth *util2.AuthConfig) (string, error)
 }
 
 type RedisFailoverChecker struct {
@@ -52,101 +65,199 @@ func (r RedisFailoverChecker) CheckRedisNumber(rf *v1alpha1.RedisFailover) error
 	if err != nil {
 		return err
 	}
-	if rf.Spec.Redis.Replicas != *ss.Spec.Replicas {
+	// the StatefulSet keeps more replicas than the spec until a scale-down has moved the master away
+	if rf.Spec.Redis.Replicas > *ss.Spec.Replicas {
 		return errors.New("number  of stateful differ from spec")
 	}
-	if rf.Spec.Redis.Replicas != ss.Status.ReadyReplicas {
+	if *ss.Spec.Replicas != ss.Status.ReadyReplicas {
 		return errors.New("waiting all of redis pods become ready")
 	}
 	return nil
 }
 
 func (r RedisFailoverChecker) CheckSentinelNumber(rf *v1alpha1.RedisFailover) error {
-	deploy, err := r.K8SService.GetDeployment(rf.Namespace, util2.GetSentinelName(rf))
+	deploy, err := r.K8SService.GetDeployment(rf.Namespace, util2.GetSentinelGroupName(rf))
+	if err != nil {
+		return err
+	}
+	replicas, err := getSentinelGroupReplicas(r.K8SService, rf)
 	if err != nil {
 		return err
 	}
-	if rf.Spec.Redis.Replicas != *deploy.Spec.Replicas {
+	if replicas != *deploy.Spec.Replicas {
 		return errors.New("number of sentinel pos differ from spec")
 	}
 	return err
 }
 
 func (r RedisFailoverChecker) CheckSentinelReadyReplicas(rf *v1alpha1.RedisFailover) error {
-	d, err := r.K8SService.GetDeployment(rf.Namespace, util.GetSentinelName(rf))
+	d, err := r.K8SService.GetDeployment(rf.Namespace, util.GetSentinelGroupName(rf))
+	if err != nil {
+		return err
+	}
+	replicas, err := getSentinelGroupReplicas(r.K8SService, rf)
 	if err != nil {
 		return err
 	}
-	if rf.Spec.Sentinel.Replicas != d.Status.ReadyReplicas {
+	if replicas != d.Status.ReadyReplicas {
 		return errors.New("waiting all of sentinel pods become ready")
 	}
 	return nil
 }
 
+// CheckSharedSentinels checks the sentinels of Sentinel.Shared can monitor the instance: the RedisFailover
+// running them exists and runs its own sentinels, and no older instance they monitor has the same master name
+func (r RedisFailoverChecker) CheckSharedSentinels(rf *v1alpha1.RedisFailover) error {
+	if rf.Spec.Sentinel.Shared == "" {
+		return nil
+	}
+	group, err := listSentinelGroup(r.K8SService, rf)
+	if err != nil {
+		return err
+	}
+	masterName := util2.GetSentinelMasterName(rf)
+	for i := range group {
+		other := &group[i]
+		switch {
+		case other.Name == rf.Name:
+		case other.Name == rf.Spec.Sentinel.Shared:
+			if other.Spec.Sentinel.Shared != "" {
+				return fmt.Errorf("sentinels of %s are shared from %s, they can't be shared again", other.Name, other.Spec.Sentinel.Shared)
+			}
+			if util2.GetSentinelMasterName(other) == masterName {
+				return fmt.Errorf("master name %s is used by %s", masterName, other.Name)
+			}
+		case util2.GetSentinelMasterName(other) == masterName && isOlderRedisFailover(other, rf):
+			return fmt.Errorf("master name %s is used by %s", masterName, other.Name)
+		}
+	}
+	return nil
+}
+
 func (r RedisFailoverChecker) CheckAllSlavesFromMaster(master string, rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) error {
 	rips, err := r.GetRedisesIPs(rf, auth)
 	if err != nil {
 		return err
 	}
+	masterHost, masterPort, err := r.GetRedisAddress(rf, master)
+	if err != nil {
+		return err
+	}
 	for _, rip := range rips {
-		slave, err := r.RedisClient.GetSlaveMasterIP(rip, auth)
+		info, err := r.RedisClient.GetReplicationInfo(rip, auth)
 		if err != nil {
 			return err
 		}
-		if slave != "" && slave != master {
-			return fmt.Errorf("slave %s don't have the master %s, has %s", rip, master, slave)
+		if !info.Master && (info.MasterHost != masterHost || info.MasterPort != masterPort) {
+			return fmt.Errorf("slave %s don't have the master %s, has %s:%s", rip, master, info.MasterHost, info.MasterPort)
+		}
+	}
+	return nil
+}
+
+// CheckReplicasInSync checks every replica is connected to the master and at most maxReplicaLag bytes behind it
+func (r RedisFailoverChecker) CheckReplicasInSync(master string, rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) error {
+	masterInfo, err := r.RedisClient.GetReplicationInfo(master, auth)
+	if err != nil {
+		return err
+	}
+	rips, err := r.GetRedisesIPs(rf, auth)
+	if err != nil {
+		return err
+	}
+	for _, rip := range rips {
+		if rip == master {
+			continue
+		}
+		info, err := r.RedisClient.GetReplicationInfo(rip, auth)
+		if err != nil {
+			return err
+		}
+		if !info.MasterLinkUp {
+			return fmt.Errorf("replica %s is not connected to the master %s", rip, master)
+		}
+		if lag := masterInfo.Offset - info.Offset; lag > maxReplicaLag {
+			return fmt.Errorf("replica %s is %d bytes behind the master %s", rip, lag, master)
+		}
+	}
+	return nil
+}
+
+// CheckSentinelsAgreeOnMaster checks every sentinel monitors the given master
+func (r RedisFailoverChecker) CheckSentinelsAgreeOnMaster(rf *v1alpha1.RedisFailover, master string, sentinels []string, auth *util2.AuthConfig) error {
+	monitor, monitorPort, err := r.GetRedisAddress(rf, master)
+	if err != nil {
+		return err
+	}
+	for _, sip := range sentinels {
+		if err := r.CheckSentinelMonitor(sip, monitor, monitorPort, auth); err != nil {
+			return fmt.Errorf("sentinel %s: %v", sip, err)
 		}
 	}
 	return nil
 }
 
 func (r RedisFailoverChecker) CheckSentinelNumberInMemory(sentinel string, rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) error {
+	replicas, err := getSentinelGroupReplicas(r.K8SService, rf)
+	if err != nil {
+		return err
+	}
 	nSentinels, err := r.RedisClient.GetNumberSentinelsInMemory(sentinel, auth)
 	if err != nil {
 		return err
-	} else if nSentinels != rf.Spec.Sentinel.Replicas {
+	} else if nSentinels != replicas {
 		return errors.New("sentinels in memory mismatch")
 	}
 	return nil
 }
 
 func (r RedisFailoverChecker) CheckSentinelSlavesNumberInMemory(sentinel string, rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) error {
+	rips, err := r.GetRedisesIPs(rf, auth)
+	if err != nil {
+		return err
+	}
 	nSlaves, err := r.RedisClient.GetNumberSentinelSlavesInMemory(sentinel, auth)
 	if err != nil {
 		return err
-	} else if nSlaves != rf.Spec.Redis.Replicas-1 {
+	} else if nSlaves != int32(len(rips))-1 {
 		return errors.New("sentinel's slaves in memory mismatch")
 	}
 	return nil
 }
 
-func (r RedisFailoverChecker) CheckSentinelMonitor(sentinel string, monitor string, auth *util2.AuthConfig) error {
-	actualMonitorIP, err := r.RedisClient.GetSentinelMonitor(sentinel, auth)
+func (r RedisFailoverChecker) CheckSentinelMonitor(sentinel string, monitor string, monitorPort string, auth *util2.AuthConfig) error {
+	actualMonitorIP, actualMonitorPort, err := r.RedisClient.GetSentinelMonitor(sentinel, auth)
 	if err != nil {
 		return err
 	}
-	if actualMonitorIP != monitor {
+	if actualMonitorIP != monitor || actualMonitorPort != monitorPort {
 		return errors.New("the monitor on the sentinel config does not match with the expected one")
 	}
 	return nil
 }
 
-func (r RedisFailoverChecker) GetMasterIP(rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) (string, error) {
-	rips, err := r.GetRedisesIPs(rf, auth)
+// CheckSentinelConfig checks the settings of the master SENTINEL MASTER reports against the sentinel fields
+func (r RedisFailoverChecker) CheckSentinelConfig(sentinel string, rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) error {
+	actual, err := r.RedisClient.GetSentinelMasterConfig(sentinel, auth)
 	if err != nil {
-		return "", err
+		return err
 	}
-	masters := []string{}
-	for _, rip := range rips {
-		master, err := r.RedisClient.IsMaster(rip, auth)
-		if err != nil {
-			return "", err
+	for key, value := range getSentinelConfig(rf) {
+		if !sentinelReportedConfigs[key] {
+			continue
 		}
-		if master {
-			masters = append(masters, rip)
+		if actual[key] != value {
+			return fmt.Errorf("sentinel %s %s conflict, expect: %s, current: %s", sentinel, key, value, actual[key])
 		}
 	}
+	return nil
+}
 
+func (r RedisFailoverChecker) GetMasterIP(rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) (string, error) {
+	masters, err := r.GetMastersIPs(rf, auth)
+	if err != nil {
+		return "", err
+	}
 	if len(masters) != 1 {
 		return "", errors.New("number of redis nodes known as master is different than 1")
 	}
@@ -154,21 +265,27 @@ func (r RedisFailoverChecker) GetMasterIP(rf *v1alpha1.RedisFailover, auth *util
 }
 
 func (r RedisFailoverChecker) GetNumberMasters(rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) (int, error) {
-	nMasters := 0
+	masters, err := r.GetMastersIPs(rf, auth)
+	return len(masters), err
+}
+
+// GetMastersIPs returns the IPs of the redis pods acting as master, more than one after a split brain
+func (r RedisFailoverChecker) GetMastersIPs(rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) ([]string, error) {
 	rips, err := r.GetRedisesIPs(rf, auth)
 	if err != nil {
-		return nMasters, err
+		return nil, err
 	}
+	masters := []string{}
 	for _, rip := range rips {
 		master, err := r.RedisClient.IsMaster(rip, auth)
 		if err != nil {
-			return nMasters, err
+			return nil, err
 		}
 		if master {
-			nMasters++
+			masters = append(masters, rip)
 		}
 	}
-	return nMasters, err
+	return masters, nil
 }
 
 func (r RedisFailoverChecker) GetRedisesIPs(rf *v1alpha1.RedisFailover, auth *util2.AuthConfig) ([]string, error) {
@@ -187,7 +304,7 @@ func (r RedisFailoverChecker) GetRedisesIPs(rf *v1alpha1.RedisFailover, auth *ut
 
 func (r RedisFailoverChecker) GetSentinelsIPs(rf *v1alpha1.RedisFailover) ([]string, error) {
 	sentinels := []string{}
-	rps, err := r.K8SService.GetDeploymentPods(rf.Namespace, util2.GetSentinelName(rf))
+	rps, err := r.K8SService.GetDeploymentPods(rf.Namespace, util2.GetSentinelGroupName(rf))
 	if err != nil {
 		return nil, err
 	}
@@ -218,6 +335,34 @@ func (r RedisFailoverChecker) GetMinimumRedisPodTime(rf *v1alpha1.RedisFailover)
 	return minTime, nil
 }
 
+// GetRedisAddress returns the address replicas and sentinels reach the redis pod of the given IP on
+func (r RedisFailoverChecker) GetRedisAddress(rf *v1alpha1.RedisFailover, ip string) (string, string, error) {
+	return getRedisAddress(r.K8SService, rf, ip)
+}
+
+// GetRedisVersion returns the redis_version the given redis reports
+func (r RedisFailoverChecker) GetRedisVersion(ip string, auth *util2.AuthConfig) (string, error) {
+	server, err := r.RedisClient.GetServerInfo(ip, auth)
+	if err != nil {
+		return "", err
+	}
+	return server.Version, nil
+}
+
+// GetSentinelVersion returns the redis_version the given sentinel reports
+func (r RedisFailoverChecker) GetSentinelVersion(ip string, auth *util2.AuthConfig) (string, error) {
+	server, err := r.RedisClient.GetSentinelServerInfo(ip, auth)
+	if err != nil {
+		return "", err
+	}
+	return server.Version, nil
+}
+
+// IsRedisRestoreStaged reports whether the restore Job seeded the first redis pod with the backup
+func (r RedisFailoverChecker) IsRedisRestoreStaged(rf *v1alpha1.RedisFailover) (bool, error) {
+	return isRedisRestoreStaged(r.K8SService, rf)
+}
+
 func (r RedisFailoverChecker) CheckRedisConfig(rf *v1alpha1.RedisFailover, addr string, auth *util2.AuthConfig) error {
 	client := goredis.NewClient(&goredis.Options{
 		Addr:     net.JoinHostPort(addr, "6379"),
@@ -225,12 +370,17 @@ func (r RedisFailoverChecker) CheckRedisConfig(rf *v1alpha1.RedisFailover, addr
 		DB:       0,
 	})
 	defer client.Close()
+	redis.RenameCommands(client, auth.ForRedis(addr))
 	configs, err := r.RedisClient.GetAllRedisConfig(client)
 	if err != nil {
 		return err
 	}
+	config, err := loadRedisConfig(r.K8SService, rf)
+	if err != nil {
+		return err
+	}
 
-	for key, value := range rf.Spec.Redis.CustomConfig {
+	for key, value := range getRedisLiveConfig(rf, config) {
 		var err error
 		if _, ok := parseConfigMap[key]; ok {
 			value, err = util.ParseRedisMemConf(value)
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"encoding/json"
 	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
 	"github.com/DevineLiu/redis-operator/controllers/middle/client/k8s"
 	util2 "github.com/DevineLiu/redis-operator/controllers/util"
@@ -29,6 +30,12 @@ type RedisFailoverClient interface {
 	EnsureRedisConfigMap(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error
 	EnsureNotPresentRedisService(rf *middlev1alpha1.RedisFailover) error
 	EnsurePasswordSecrets(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error
+	EnsureRedisBackupSchedules(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error
+	EnsureRedisRestore(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error
+	EnsureRedisFinalBackup(rf *middlev1alpha1.RedisFailover) (*middlev1alpha1.RedisBackup, error)
+	EnsureSentinelStopped(rf *middlev1alpha1.RedisFailover) (bool, error)
+	EnsureRedisStopped(rf *middlev1alpha1.RedisFailover) (bool, error)
+	EnsureRedisVolumesReleased(rf *middlev1alpha1.RedisFailover) error
 }
 
 type RedisFailoverKubeClient struct {
@@ -63,7 +70,7 @@ func (r RedisFailoverKubeClient) EnsureSentinelHeadlessService(rf *middlev1alpha
 
 func (r RedisFailoverKubeClient) EnsureSentinelConfigMap(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
 	cm := generateSentinelConfigMap(rf, labels, ownerRefs)
-	return r.K8SService.CreateIfNotExistsConfigMap(rf.Namespace, cm)
+	return r.K8SService.CreateOrUpdateConfigMap(rf.Namespace, cm)
 }
 
 func (r RedisFailoverKubeClient) EnsureSentinelProbeConfigMap(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
@@ -72,7 +79,7 @@ func (r RedisFailoverKubeClient) EnsureSentinelProbeConfigMap(rf *middlev1alpha1
 }
 
 func (r RedisFailoverKubeClient) EnsureSentinelDeployment(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
-	if err := r.ensurePodDisruptionBudget(rf, util2.RedisName, util2.RedisRoleName, labels, ownerRefs); err != nil {
+	if err := r.ensurePodDisruptionBudget(rf, util2.SentinelName, util2.SentinelRoleName, labels, ownerRefs); err != nil {
 		return err
 	}
 	oldSs, err := r.K8SService.GetDeployment(rf.Namespace, util2.GetSentinelName(rf))
@@ -83,31 +90,52 @@ func (r RedisFailoverKubeClient) EnsureSentinelDeployment(rf *middlev1alpha1.Red
 		}
 		return err
 	}
-	if shouldUpdateRedis(rf.Spec.Sentinel.Resources, oldSs.Spec.Template.Spec.Containers[0].Resources,
-		rf.Spec.Sentinel.Replicas, *oldSs.Spec.Replicas) {
-		deploy := generateSentinelDeployment(rf, labels, ownerRefs)
+	deploy := generateSentinelDeployment(rf, labels, ownerRefs)
+	if shouldUpdateWorkload(&deploy.ObjectMeta, &oldSs.ObjectMeta, *deploy.Spec.Replicas, *oldSs.Spec.Replicas) {
+		deploy.ResourceVersion = oldSs.ResourceVersion
 		return r.K8SService.UpdateDeployment(rf.Namespace, deploy)
 	}
 	return nil
 }
 
 func (r RedisFailoverKubeClient) EnsureRedisStatefulSet(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
-	if err := r.ensurePodDisruptionBudget(rf, util2.SentinelName, util2.SentinelRoleName, labels, ownerRefs); err != nil {
+	if err := r.ensurePodDisruptionBudget(rf, util2.RedisName, util2.RedisRoleName, labels, ownerRefs); err != nil {
 		return err
 	}
 	oldSs, err := r.K8SService.GetStatefulSet(rf.Namespace, util2.GetRedisName(rf))
 	if err != nil {
 		// If no resource we need to create.
 		if errors.IsNotFound(err) {
+			// hold the creation until the backup to restore is staged on the first pod's volume
+			if IsRedisRestorePending(rf) {
+				staged, err := isRedisRestoreStaged(r.K8SService, rf)
+				if err != nil || !staged {
+					return err
+				}
+			}
 			ss := generateRedisStatefulSet(rf, labels, ownerRefs)
+			if err := r.setRedisConfigHash(rf, ss); err != nil {
+				return err
+			}
 			return r.K8SService.CreateStatefulSet(rf.Namespace, ss)
 		}
 
 		return err
 	}
-	if shouldUpdateRedis(rf.Spec.Redis.Resources, oldSs.Spec.Template.Spec.Containers[0].Resources,
-		rf.Spec.Redis.Replicas, *oldSs.Spec.Replicas) || exporterChanged(rf, oldSs) {
-		ss := generateRedisStatefulSet(rf, labels, ownerRefs)
+	ss := generateRedisStatefulSet(rf, labels, ownerRefs)
+	if err := r.setRedisConfigHash(rf, ss); err != nil {
+		return err
+	}
+	if *ss.Spec.Replicas < *oldSs.Spec.Replicas {
+		// the pods are removed by RedisFailoverHandler.ScaleDownRedis once the master is not among them
+		replicas := *oldSs.Spec.Replicas
+		ss.Spec.Replicas = &replicas
+	}
+	if shouldUpdateWorkload(&ss.ObjectMeta, &oldSs.ObjectMeta, *ss.Spec.Replicas, *oldSs.Spec.Replicas) ||
+		ss.Spec.UpdateStrategy.Type != oldSs.Spec.UpdateStrategy.Type {
+		ss.ResourceVersion = oldSs.ResourceVersion
+		// the volume claim templates of a StatefulSet are immutable
+		ss.Spec.VolumeClaimTemplates = oldSs.Spec.VolumeClaimTemplates
 		return r.K8SService.UpdateStatefulSet(rf.Namespace, ss)
 	}
 	return nil
@@ -118,24 +146,27 @@ func (r RedisFailoverKubeClient) EnsureRedisService(rf *middlev1alpha1.RedisFail
 	return r.K8SService.CreateIfNotExistsService(rf.Namespace, svc)
 }
 
-func (r RedisFailoverKubeClient) EnsureRedisNodePortService(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
-	panic("implement me")
-}
-
 func (r RedisFailoverKubeClient) EnsureRedisShutdownConfigMap(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
 	if rf.Spec.Redis.ShutdownConfigMap != "" {
 		if _, err := r.K8SService.GetConfigMap(rf.Namespace, rf.Spec.Redis.ShutdownConfigMap); err != nil {
 			return err
 		}
 	} else {
+		// the script follows the renames of Redis.CustomCommandRenames
 		cm := generateRedisShutdownConfigMap(rf, labels, ownerRefs)
-		return r.K8SService.CreateIfNotExistsConfigMap(rf.Namespace, cm)
+		return r.K8SService.CreateOrUpdateConfigMap(rf.Namespace, cm)
 	}
 	return nil
 }
 
+// EnsureRedisConfigMap renders the redis.conf mounted by the redis pods
 func (r RedisFailoverKubeClient) EnsureRedisConfigMap(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
-	panic("implement me")
+	config, err := loadRedisConfig(r.K8SService, rf)
+	if err != nil {
+		return err
+	}
+	cm := generateRedisConfigMap(rf, config, labels, ownerRefs)
+	return r.K8SService.CreateOrUpdateConfigMap(rf.Namespace, cm)
 }
 
 func (r RedisFailoverKubeClient) EnsureNotPresentRedisService(rf *middlev1alpha1.RedisFailover) error {
@@ -202,39 +233,36 @@ func (r RedisFailoverKubeClient) ensurePodDisruptionBudget(rf *middlev1alpha1.Re
 	return r.K8SService.CreateIfNotExistsPodDisruptionBudget(namespace, pdb)
 }
 
-func shouldUpdateRedis(expectResource, containterResource corev1.ResourceRequirements, expectSize, replicas int32) bool {
-	if expectSize != replicas {
-		return true
-	}
-	if result := containterResource.Requests.Cpu().Cmp(*expectResource.Requests.Cpu()); result != 0 {
-		return true
+// setRedisConfigHash annotates the redis pods with the hash of the settings of redis.conf only a restart applies
+func (r RedisFailoverKubeClient) setRedisConfigHash(rf *middlev1alpha1.RedisFailover, ss *appsv1.StatefulSet) error {
+	config, err := loadRedisConfig(r.K8SService, rf)
+	if err != nil {
+		return err
 	}
-	if result := containterResource.Requests.Memory().Cmp(*expectResource.Requests.Memory()); result != 0 {
-		return true
+	hash, err := getRedisRestartConfigHash(config)
+	if err != nil {
+		return err
 	}
-	if result := containterResource.Limits.Cpu().Cmp(*expectResource.Limits.Cpu()); result != 0 {
-		return true
+	annotations := map[string]string{
+		RedisConfigHashAnnotation: hash,
 	}
-	if result := containterResource.Limits.Memory().Cmp(*expectResource.Limits.Memory()); result != 0 {
-		return true
+	if renames := getRedisConfigCommandRenames(config); len(renames) > 0 {
+		data, err := json.Marshal(renames)
+		if err != nil {
+			return err
+		}
+		annotations[RedisCommandRenamesAnnotation] = string(data)
 	}
-	return false
+	ss.Spec.Template.Annotations = util2.MergeMap(ss.Spec.Template.Annotations, annotations)
+	setTemplateHash(&ss.ObjectMeta, ss.Spec.Template)
+	return nil
 }
 
-func exporterChanged(rf *middlev1alpha1.RedisFailover, sts *appsv1.StatefulSet) bool {
-	if rf.Spec.Redis.Exporter.Enabled {
-		for _, container := range sts.Spec.Template.Spec.Containers {
-			if container.Name == exporterContainerName {
-				return false
-			}
-		}
+// shouldUpdateWorkload reports whether the live workload runs another pod template or replica count
+// than the expected one. Workloads created before the template hash was recorded are updated once.
+func shouldUpdateWorkload(expected, live *metav1.ObjectMeta, expectedReplicas, replicas int32) bool {
+	if expectedReplicas != replicas {
 		return true
-	} else {
-		for _, container := range sts.Spec.Template.Spec.Containers {
-			if container.Name == exporterContainerName {
-				return true
-			}
-		}
-		return false
 	}
+	return expected.Annotations[TemplateHashAnnotation] != live.Annotations[TemplateHashAnnotation]
 }
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/client/k8s"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RedisConfigHashAnnotation records on the redis pods the hash of the settings of redis.conf that cannot
+// be changed with CONFIG SET, the pods are restarted when it changes
+const RedisConfigHashAnnotation = "middle.alauda.cn/redis-config-hash"
+
+// RedisCommandRenamesAnnotation records on the redis pods the renames of commands of the redis.conf they were
+// started with, the operator talks to every pod with its own renames until a rolling restart applies new ones
+const RedisCommandRenamesAnnotation = "middle.alauda.cn/command-renames"
+
+// defaultRedisReplicaPriority is the slave-priority of redis when redis.conf does not set it
+const defaultRedisReplicaPriority = 100
+
+// defaultRedisConfig holds the settings of redis.conf that are neither in Redis.ConfigConfigMap nor in Redis.CustomConfig
+var defaultRedisConfig = map[string][]string{
+	"tcp-keepalive": {"60"},
+}
+
+// redisStaticConfigs are the settings CONFIG SET refuses, they only take effect from redis.conf at start.
+// replicaof is left to the operator, which points the replicas at the master.
+var redisStaticConfigs = map[string]bool{
+	"bind":                true,
+	"port":                true,
+	"tcp-backlog":         true,
+	"unixsocket":          true,
+	"unixsocketperm":      true,
+	"daemonize":           true,
+	"supervised":          true,
+	"pidfile":             true,
+	"logfile":             true,
+	"syslog-enabled":      true,
+	"syslog-ident":        true,
+	"syslog-facility":     true,
+	"databases":           true,
+	"always-show-logo":    true,
+	"rename-command":      true,
+	"include":             true,
+	"loadmodule":          true,
+	"io-threads":          true,
+	"io-threads-do-reads": true,
+	"aclfile":             true,
+	"disable-thp":         true,
+	"cluster-enabled":     true,
+	"cluster-config-file": true,
+	"appendfilename":      true,
+	"appenddirname":       true,
+	"replicaof":           true,
+	"slaveof":             true,
+	"user":                true,
+	"set-proc-title":      true,
+	"proc-title-template": true,
+	"ignore-warnings":     true,
+}
+
+// isRedisStaticConfig reports whether the setting can only be changed by restarting redis
+func isRedisStaticConfig(key string) bool {
+	return redisStaticConfigs[strings.ToLower(key)]
+}
+
+// loadRedisConfig merges the settings of redis.conf: the operator defaults, overridden by the redis.conf key of
+// Redis.ConfigConfigMap, overridden by Redis.Persistence and Redis.CustomConfig. Every setting maps to its lines, in order.
+func loadRedisConfig(k8SService k8s.Services, rf *middlev1alpha1.RedisFailover) (map[string][]string, error) {
+	config := map[string][]string{}
+	for key, values := range defaultRedisConfig {
+		config[key] = values
+	}
+	if rf.Spec.Redis.ConfigConfigMap != "" {
+		cm, err := k8SService.GetConfigMap(rf.Namespace, rf.Spec.Redis.ConfigConfigMap)
+		if err != nil {
+			return nil, err
+		}
+		content, ok := cm.Data[util2.RedisConfigFileName]
+		if !ok {
+			return nil, fmt.Errorf("configmap %s has no %s key", rf.Spec.Redis.ConfigConfigMap, util2.RedisConfigFileName)
+		}
+		for key, values := range parseRedisConfig(content) {
+			config[key] = values
+		}
+	}
+	for key, value := range getRedisPersistenceConfig(rf) {
+		if key == "save" {
+			// a single save line of redis.conf only takes one <seconds> <changes> pair
+			config[key] = splitSaveConfig(value)
+			continue
+		}
+		config[key] = []string{value}
+	}
+	for key, value := range rf.Spec.Redis.CustomConfig {
+		config[strings.ToLower(key)] = []string{value}
+	}
+	if renames := rf.Spec.Redis.CustomCommandRenames; len(renames) > 0 {
+		config["rename-command"] = mergeCommandRenames(config["rename-command"], renames)
+	}
+	return config, nil
+}
+
+// mergeCommandRenames appends the renames of Redis.CustomCommandRenames to the rename-command lines,
+// dropping the lines renaming the same commands since redis refuses to rename a command twice
+func mergeCommandRenames(lines []string, renames []middlev1alpha1.RedisCommandRename) []string {
+	renamed := map[string]bool{}
+	for _, rename := range renames {
+		renamed[strings.ToLower(rename.From)] = true
+	}
+	merged := []string{}
+	for _, line := range lines {
+		if fields := strings.Fields(line); len(fields) > 0 && renamed[strings.ToLower(fields[0])] {
+			continue
+		}
+		merged = append(merged, line)
+	}
+	for _, rename := range renames {
+		merged = append(merged, fmt.Sprintf("%s %q", rename.From, rename.To))
+	}
+	return merged
+}
+
+// getRedisCommandName returns the name the redis pods know the command by, for the scripts and probes
+func getRedisCommandName(rf *middlev1alpha1.RedisFailover, name string) string {
+	auth := util2.AuthConfig{CommandRenames: util2.GetRedisCommandRenames(rf)}
+	return auth.Command(name)
+}
+
+// GetRedisReplicaPriority returns the slave-priority of redis.conf, the one the replicas are given back once no
+// longer kept from being promoted
+func GetRedisReplicaPriority(k8SService k8s.Services, rf *middlev1alpha1.RedisFailover) (int, error) {
+	config, err := loadRedisConfig(k8SService, rf)
+	if err != nil {
+		return 0, err
+	}
+	priority := defaultRedisReplicaPriority
+	for _, key := range []string{"slave-priority", "replica-priority"} {
+		values := config[key]
+		if len(values) == 0 {
+			continue
+		}
+		if priority, err = strconv.Atoi(values[len(values)-1]); err != nil {
+			return 0, fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	return priority, nil
+}
+
+// getRedisConfigCommandRenames returns the rename-command lines of redis.conf by lower case command name
+func getRedisConfigCommandRenames(config map[string][]string) map[string]string {
+	renames := map[string]string{}
+	for _, line := range config["rename-command"] {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		renames[strings.ToLower(fields[0])] = unquoteRedisConfigValue(fields[1])
+	}
+	return renames
+}
+
+// unquoteRedisConfigValue returns the value of a double or single quoted argument of redis.conf, the argument
+// itself otherwise
+func unquoteRedisConfigValue(value string) string {
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// GetRedisPodCommandRenames returns the renames of commands the redis pod was started with, none when it is not annotated
+func GetRedisPodCommandRenames(pod *corev1.Pod) (map[string]string, error) {
+	renames := map[string]string{}
+	value, ok := pod.Annotations[RedisCommandRenamesAnnotation]
+	if !ok {
+		return renames, nil
+	}
+	if err := json.Unmarshal([]byte(value), &renames); err != nil {
+		return nil, fmt.Errorf("pod %s: %s annotation: %v", pod.Name, RedisCommandRenamesAnnotation, err)
+	}
+	return renames, nil
+}
+
+// SetRedisPodCommandRenames fills auth with the renames of commands every redis pod was started with. The sentinels
+// are told the renames most pods run: once a rolling restart applying new renames only has the master left, those of
+// the replicas a failover promotes.
+func SetRedisPodCommandRenames(k8SService k8s.Services, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
+	pods, err := k8SService.GetStatefulSetPods(rf.Namespace, util2.GetRedisName(rf))
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	auth.PodCommandRenames = map[string]map[string]string{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.PodIP == "" {
+			continue
+		}
+		renames, err := GetRedisPodCommandRenames(pod)
+		if err != nil {
+			return err
+		}
+		auth.PodCommandRenames[pod.Status.PodIP] = renames
+	}
+	auth.SentinelCommandRenames = util2.GetSentinelCommandRenames(getMostCommonCommandRenames(auth.PodCommandRenames, auth.CommandRenames))
+	return nil
+}
+
+// getMostCommonCommandRenames returns the renames the most pods run, the given ones on a tie or without pods
+func getMostCommonCommandRenames(pods map[string]map[string]string, renames map[string]string) map[string]string {
+	counts := map[string]int{}
+	sets := map[string]map[string]string{}
+	for _, podRenames := range pods {
+		// maps are printed sorted by key
+		key := fmt.Sprint(podRenames)
+		counts[key]++
+		sets[key] = podRenames
+	}
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	best := fmt.Sprint(renames)
+	sets[best] = renames
+	for _, key := range keys {
+		if counts[key] > counts[best] {
+			best = key
+		}
+	}
+	return sets[best]
+}
+
+// parseRedisConfig splits the lines of a redis.conf by setting, a setting repeated over several lines keeps all of them
+func parseRedisConfig(content string) map[string][]string {
+	config := map[string][]string{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		key := strings.ToLower(fields[0])
+		value := ""
+		if len(fields) == 2 {
+			value = strings.TrimSpace(fields[1])
+		}
+		config[key] = append(config[key], value)
+	}
+	return config
+}
+
+// getRedisPersistenceConfig returns the settings of Redis.Persistence the way CONFIG GET reports them
+func getRedisPersistenceConfig(rf *middlev1alpha1.RedisFailover) map[string]string {
+	persistence := rf.Spec.Redis.Persistence
+	config := map[string]string{
+		"save":       "",
+		"appendonly": "no",
+	}
+	switch persistence.Mode {
+	case middlev1alpha1.RedisPersistenceModeRDB, middlev1alpha1.RedisPersistenceModeBoth:
+		points := []string{}
+		for _, point := range persistence.Save {
+			points = append(points, fmt.Sprintf("%d %d", point.Seconds, point.Changes))
+		}
+		config["save"] = strings.Join(points, " ")
+	}
+	switch persistence.Mode {
+	case middlev1alpha1.RedisPersistenceModeAOF, middlev1alpha1.RedisPersistenceModeBoth:
+		config["appendonly"] = "yes"
+		config["appendfsync"] = persistence.AppendFsync
+	}
+	return config
+}
+
+// getRedisLiveConfig returns the settings of redis.conf CONFIG SET applies to the running pods, every one that is
+// not static, the lines of a setting joined the way CONFIG GET reports them
+func getRedisLiveConfig(rf *middlev1alpha1.RedisFailover, config map[string][]string) map[string]string {
+	liveConfig := map[string]string{}
+	for key, values := range config {
+		if isRedisStaticConfig(key) {
+			continue
+		}
+		unquoted := make([]string, 0, len(values))
+		for _, value := range values {
+			unquoted = append(unquoted, unquoteRedisConfigValue(value))
+		}
+		liveConfig[key] = strings.Join(unquoted, " ")
+	}
+	for key, value := range getRedisPersistenceConfig(rf) {
+		liveConfig[key] = value
+	}
+	// the operator sets these on the running pods: a priority of 0 keeps replicas out of a failover,
+	// exposed pods announce their Service
+	if len(rf.Status.ExcludedReplicas) > 0 {
+		delete(liveConfig, "slave-priority")
+		delete(liveConfig, "replica-priority")
+	}
+	if rf.Spec.Expose != nil {
+		for _, key := range []string{"slave-announce-ip", "slave-announce-port", "replica-announce-ip", "replica-announce-port"} {
+			delete(liveConfig, key)
+		}
+	}
+	return liveConfig
+}
+
+func splitSaveConfig(value string) []string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		// disables the RDB snapshots
+		return []string{`""`}
+	}
+	if len(fields)%2 != 0 {
+		return []string{value}
+	}
+	lines := []string{}
+	for i := 0; i < len(fields); i += 2 {
+		lines = append(lines, fmt.Sprintf("%s %s", fields[i], fields[i+1]))
+	}
+	return lines
+}
+
+// renderRedisConfig writes the settings as a redis.conf, sorted so the content is stable
+func renderRedisConfig(config map[string][]string) string {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		for _, value := range config[key] {
+			fmt.Fprintf(&b, "%s %s\n", key, value)
+		}
+	}
+	return b.String()
+}
+
+// getRedisRestartConfigHash hashes the static settings, the only ones a restart applies, the others are applied
+// to the running pods by getRedisLiveConfig
+func getRedisRestartConfigHash(config map[string][]string) (string, error) {
+	restartConfig := map[string][]string{}
+	for key, values := range config {
+		if isRedisStaticConfig(key) {
+			restartConfig[key] = values
+		}
+	}
+	return util2.GenerateObjectHash(restartConfig)
+}
+
+func generateRedisConfigMap(rf *middlev1alpha1.RedisFailover, config map[string][]string, labels map[string]string, ownerRefs []metav1.OwnerReference) *corev1.ConfigMap {
+	labels = util2.MergeMap(labels, generateSelectorLabels(util2.RedisRoleName, rf.Name))
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            util2.GetRedisConfigMapName(rf),
+			Namespace:       rf.Namespace,
+			Labels:          labels,
+			OwnerReferences: ownerRefs,
+		},
+		Data: map[string]string{
+			util2.RedisConfigFileName: renderRedisConfig(config),
+		},
+	}
+}
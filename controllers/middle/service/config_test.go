@@ -0,0 +1,308 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+)
+
+func TestParseRedisConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string][]string
+	}{
+		{
+			name:    "empty",
+			content: "",
+			want:    map[string][]string{},
+		},
+		{
+			name:    "comments and blank lines",
+			content: "# maxmemory 1gb\n\n   \n  # indented comment\nmaxmemory 2gb\n",
+			want:    map[string][]string{"maxmemory": {"2gb"}},
+		},
+		{
+			name:    "keys are lower cased, values kept",
+			content: "MaxMemory-Policy AllKeys-LRU",
+			want:    map[string][]string{"maxmemory-policy": {"AllKeys-LRU"}},
+		},
+		{
+			name:    "repeated keys keep their lines in order",
+			content: "save 900 1\nsave 300 10\nsave 60 10000",
+			want:    map[string][]string{"save": {"900 1", "300 10", "60 10000"}},
+		},
+		{
+			name:    "value with spaces",
+			content: "rename-command  CONFIG   \"\"  \n",
+			want:    map[string][]string{"rename-command": {"CONFIG   \"\""}},
+		},
+		{
+			name:    "key without value",
+			content: "appendonly\r\n",
+			want:    map[string][]string{"appendonly": {""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRedisConfig(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRedisConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderRedisConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string][]string
+		want   string
+	}{
+		{
+			name:   "empty",
+			config: map[string][]string{},
+			want:   "",
+		},
+		{
+			name:   "keys sorted",
+			config: map[string][]string{"maxmemory": {"1gb"}, "appendonly": {"yes"}, "databases": {"16"}},
+			want:   "appendonly yes\ndatabases 16\nmaxmemory 1gb\n",
+		},
+		{
+			name:   "lines of a key in order",
+			config: map[string][]string{"save": {"900 1", "300 10"}},
+			want:   "save 900 1\nsave 300 10\n",
+		},
+		{
+			name:   "key without lines",
+			config: map[string][]string{"save": {}, "port": {"6379"}},
+			want:   "port 6379\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderRedisConfig(tt.config)
+			if got != tt.want {
+				t.Errorf("renderRedisConfig() = %q, want %q", got, tt.want)
+			}
+			// what is rendered reads back the same
+			parsed := parseRedisConfig(got)
+			for key, values := range tt.config {
+				if len(values) > 0 && !reflect.DeepEqual(parsed[key], values) {
+					t.Errorf("parseRedisConfig(renderRedisConfig())[%s] = %v, want %v", key, parsed[key], values)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitSaveConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "disabled", value: "", want: []string{`""`}},
+		{name: "single point", value: "900 1", want: []string{"900 1"}},
+		{name: "several points", value: "900 1 300 10 60 10000", want: []string{"900 1", "300 10", "60 10000"}},
+		{name: "odd fields kept as is", value: "900 1 300", want: []string{"900 1 300"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitSaveConfig(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitSaveConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeCommandRenames(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		renames []middlev1alpha1.RedisCommandRename
+		want    []string
+	}{
+		{
+			name:    "no rename-command lines",
+			renames: []middlev1alpha1.RedisCommandRename{{From: "flushall", To: "f1"}},
+			want:    []string{`flushall "f1"`},
+		},
+		{
+			name:    "lines of other commands kept",
+			lines:   []string{`keys ""`},
+			renames: []middlev1alpha1.RedisCommandRename{{From: "flushall", To: "f1"}},
+			want:    []string{`keys ""`, `flushall "f1"`},
+		},
+		{
+			name:    "line of the same command replaced, whatever its case",
+			lines:   []string{`FLUSHALL other`, `keys ""`},
+			renames: []middlev1alpha1.RedisCommandRename{{From: "flushall", To: "f1"}},
+			want:    []string{`keys ""`, `flushall "f1"`},
+		},
+		{
+			name:    "disabled command",
+			renames: []middlev1alpha1.RedisCommandRename{{From: "KEYS", To: ""}},
+			want:    []string{`KEYS ""`},
+		},
+		{
+			name:    "name needing quotes",
+			renames: []middlev1alpha1.RedisCommandRename{{From: "config", To: `c "x"`}},
+			want:    []string{`config "c \"x\""`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeCommandRenames(tt.lines, tt.renames); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeCommandRenames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRedisConfigCommandRenames(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  map[string]string
+	}{
+		{name: "none", want: map[string]string{}},
+		{name: "double quoted", lines: []string{`FLUSHALL "f1"`}, want: map[string]string{"flushall": "f1"}},
+		{name: "single quoted", lines: []string{`keys 'k1'`}, want: map[string]string{"keys": "k1"}},
+		{name: "bare", lines: []string{`config c1`}, want: map[string]string{"config": "c1"}},
+		{name: "disabled", lines: []string{`keys ""`}, want: map[string]string{"keys": ""}},
+		{name: "malformed line skipped", lines: []string{`keys`, `config a b`}, want: map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getRedisConfigCommandRenames(map[string][]string{"rename-command": tt.lines})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getRedisConfigCommandRenames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetMostCommonCommandRenames(t *testing.T) {
+	spec := map[string]string{"config": "c2"}
+	old := map[string]string{"config": "c1"}
+	none := map[string]string{}
+	tests := []struct {
+		name string
+		pods map[string]map[string]string
+		want map[string]string
+	}{
+		{name: "no pod", pods: map[string]map[string]string{}, want: spec},
+		{name: "all pods on the spec", pods: map[string]map[string]string{"a": spec, "b": spec}, want: spec},
+		{name: "most pods not restarted yet", pods: map[string]map[string]string{"a": old, "b": old, "c": spec}, want: old},
+		{name: "most pods restarted", pods: map[string]map[string]string{"a": old, "b": spec, "c": spec}, want: spec},
+		{name: "tie with the spec", pods: map[string]map[string]string{"a": old, "b": spec}, want: spec},
+		{name: "pods without renames", pods: map[string]map[string]string{"a": none, "b": none, "c": spec}, want: none},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getMostCommonCommandRenames(tt.pods, spec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getMostCommonCommandRenames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRedisLiveConfig(t *testing.T) {
+	rdb := middlev1alpha1.RedisPersistence{Mode: middlev1alpha1.RedisPersistenceModeRDB, Save: []middlev1alpha1.RedisSavePoint{{Seconds: 900, Changes: 1}}}
+	tests := []struct {
+		name     string
+		config   map[string][]string
+		excluded []string
+		expose   bool
+		want     map[string]string
+	}{
+		{
+			name:   "static settings left out",
+			config: map[string][]string{"maxmemory": {"1gb"}, "port": {"6379"}, "rename-command": {`keys ""`}, "databases": {"16"}},
+			want:   map[string]string{"maxmemory": "1gb", "save": "900 1", "appendonly": "no"},
+		},
+		{
+			name:   "lines joined and unquoted",
+			config: map[string][]string{"client-output-buffer-limit": {"normal 0 0 0", "pubsub 32mb 8mb 60"}, "notify-keyspace-events": {`"Ex"`}},
+			want: map[string]string{
+				"client-output-buffer-limit": "normal 0 0 0 pubsub 32mb 8mb 60",
+				"notify-keyspace-events":     "Ex",
+				"save":                       "900 1",
+				"appendonly":                 "no",
+			},
+		},
+		{
+			name:   "persistence rendered the way CONFIG GET reports it",
+			config: map[string][]string{"save": {"900 1"}, "appendonly": {"no"}},
+			want:   map[string]string{"save": "900 1", "appendonly": "no"},
+		},
+		{
+			name:     "priority left to the operator while replicas are excluded",
+			config:   map[string][]string{"slave-priority": {"50"}},
+			excluded: []string{"redis-redis-1"},
+			want:     map[string]string{"save": "900 1", "appendonly": "no"},
+		},
+		{
+			name:   "priority applied",
+			config: map[string][]string{"slave-priority": {"50"}},
+			want:   map[string]string{"slave-priority": "50", "save": "900 1", "appendonly": "no"},
+		},
+		{
+			name:   "announced address left to the operator when exposed",
+			config: map[string][]string{"replica-announce-ip": {"10.0.0.1"}},
+			expose: true,
+			want:   map[string]string{"save": "900 1", "appendonly": "no"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &middlev1alpha1.RedisFailover{}
+			rf.Spec.Redis.Persistence = rdb
+			rf.Status.ExcludedReplicas = tt.excluded
+			if tt.expose {
+				rf.Spec.Expose = &middlev1alpha1.RedisFailoverExpose{}
+			}
+			if got := getRedisLiveConfig(rf, tt.config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getRedisLiveConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRedisRestartConfigHash(t *testing.T) {
+	base := map[string][]string{"maxmemory": {"1gb"}, "databases": {"16"}, "tcp-keepalive": {"60"}}
+	hash := func(changes map[string][]string) string {
+		config := map[string][]string{}
+		for key, values := range base {
+			config[key] = values
+		}
+		for key, values := range changes {
+			config[key] = values
+		}
+		h, err := getRedisRestartConfigHash(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	tests := []struct {
+		name        string
+		changes     map[string][]string
+		wantRestart bool
+	}{
+		{name: "no change"},
+		{name: "live setting", changes: map[string][]string{"maxmemory": {"2gb"}}},
+		{name: "live setting added", changes: map[string][]string{"maxmemory-policy": {"allkeys-lru"}}},
+		{name: "static setting", changes: map[string][]string{"databases": {"32"}}, wantRestart: true},
+		{name: "command renamed", changes: map[string][]string{"rename-command": {`keys ""`}}, wantRestart: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if restart := hash(tt.changes) != hash(nil); restart != tt.wantRestart {
+				t.Errorf("restart = %v, want %v", restart, tt.wantRestart)
+			}
+		})
+	}
+}
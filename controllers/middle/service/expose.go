@@ -0,0 +1,228 @@
+package service
+
+import (
+	"strconv"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/client/k8s"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ExposeLabel marks the Services exposing a single pod, its value is the component of the pod
+	ExposeLabel = "redis/expose"
+	// SentinelIndexLabel gives the sentinel pods a stable index the Service exposing them selects
+	SentinelIndexLabel = "redis/sentinel-index"
+	redisPort          = "6379"
+)
+
+// EnsureRedisNodePortService creates a Service of Spec.Expose.Type for every redis pod and every sentinel,
+// and deletes the ones left over by a scale-down or by removing Spec.Expose
+func (r RedisFailoverKubeClient) EnsureRedisNodePortService(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
+	desired := map[string]*corev1.Service{}
+	if rf.Spec.Expose != nil {
+		redisReplicas := rf.Spec.Redis.Replicas
+		// the pods kept until a scale-down moves the master away stay exposed
+		if ss, err := r.K8SService.GetStatefulSet(rf.Namespace, util2.GetRedisName(rf)); err == nil && *ss.Spec.Replicas > redisReplicas {
+			redisReplicas = *ss.Spec.Replicas
+		} else if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		for i := 0; i < int(redisReplicas); i++ {
+			svc := generateRedisNodePortService(rf, i, labels, ownerRefs)
+			desired[svc.Name] = svc
+		}
+		// shared sentinels are exposed by the RedisFailover running them
+		if rf.Spec.Sentinel.Shared == "" {
+			if err := r.ensureSentinelIndexes(rf); err != nil {
+				return err
+			}
+			for i := 0; i < int(rf.Spec.Sentinel.Replicas); i++ {
+				svc := generateSentinelNodePortService(rf, i, labels, ownerRefs)
+				desired[svc.Name] = svc
+			}
+		}
+	}
+
+	svcs, err := r.K8SService.ListServices(rf.Namespace)
+	if err != nil {
+		return err
+	}
+	for i := range svcs.Items {
+		svc := &svcs.Items[i]
+		if svc.Labels[ExposeLabel] == "" || svc.Labels["app.kubernetes.io/name"] != rf.Name {
+			continue
+		}
+		expected, ok := desired[svc.Name]
+		if !ok {
+			if err := r.K8SService.DeleteService(rf.Namespace, svc.Name); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			continue
+		}
+		delete(desired, svc.Name)
+		if !shouldUpdateExposeService(expected, svc) {
+			continue
+		}
+		// keep what kubernetes allocated to the Service
+		expected.ResourceVersion = svc.ResourceVersion
+		expected.Spec.ClusterIP = svc.Spec.ClusterIP
+		if expected.Spec.Ports[0].NodePort == 0 && expected.Spec.Type == svc.Spec.Type {
+			expected.Spec.Ports[0].NodePort = svc.Spec.Ports[0].NodePort
+		}
+		if err := r.K8SService.UpdateService(rf.Namespace, expected); err != nil {
+			return err
+		}
+	}
+	for _, svc := range desired {
+		if err := r.K8SService.CreateService(rf.Namespace, svc); err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureSentinelIndexes labels every sentinel pod with an index below Spec.Sentinel.Replicas, unique among the
+// pods still running. The pods of a Deployment have no stable identity the Services could select otherwise.
+func (r RedisFailoverKubeClient) ensureSentinelIndexes(rf *middlev1alpha1.RedisFailover) error {
+	pods, err := r.K8SService.GetDeploymentPods(rf.Namespace, util2.GetSentinelName(rf))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	used := map[int]bool{}
+	unindexed := []*corev1.Pod{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		index, err := strconv.Atoi(pod.Labels[SentinelIndexLabel])
+		if err != nil || index < 0 || index >= int(rf.Spec.Sentinel.Replicas) || used[index] {
+			unindexed = append(unindexed, pod)
+			continue
+		}
+		used[index] = true
+	}
+	index := 0
+	for _, pod := range unindexed {
+		for used[index] {
+			index++
+		}
+		if index >= int(rf.Spec.Sentinel.Replicas) {
+			return nil
+		}
+		used[index] = true
+		if pod.Labels == nil {
+			pod.Labels = map[string]string{}
+		}
+		pod.Labels[SentinelIndexLabel] = strconv.Itoa(index)
+		if err := r.K8SService.UpdatePod(rf.Namespace, pod); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func shouldUpdateExposeService(expected, live *corev1.Service) bool {
+	if expected.Spec.Type != live.Spec.Type || len(live.Spec.Ports) != 1 {
+		return true
+	}
+	if nodePort := expected.Spec.Ports[0].NodePort; nodePort != 0 && nodePort != live.Spec.Ports[0].NodePort {
+		return true
+	}
+	for k, v := range expected.Annotations {
+		if live.Annotations[k] != v {
+			return true
+		}
+	}
+	return false
+}
+
+// getExposedAddress returns the address the Service exposes the pod on, if it has been allocated yet
+func getExposedAddress(svc *corev1.Service, pod *corev1.Pod) (string, string, bool) {
+	if len(svc.Spec.Ports) == 0 {
+		return "", "", false
+	}
+	switch svc.Spec.Type {
+	case corev1.ServiceTypeNodePort:
+		if pod.Status.HostIP == "" || svc.Spec.Ports[0].NodePort == 0 {
+			return "", "", false
+		}
+		return pod.Status.HostIP, strconv.Itoa(int(svc.Spec.Ports[0].NodePort)), true
+	case corev1.ServiceTypeLoadBalancer:
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			host := ingress.IP
+			if host == "" {
+				host = ingress.Hostname
+			}
+			if host != "" {
+				return host, strconv.Itoa(int(svc.Spec.Ports[0].Port)), true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// getRedisAddress returns the address the redis pod of the given IP is known by: the address of its Service when
+// the instance is exposed, its pod IP otherwise. Replicas and sentinels are pointed at this address.
+func getRedisAddress(k8SService k8s.Services, rf *middlev1alpha1.RedisFailover, ip string) (string, string, error) {
+	if rf.Spec.Expose == nil {
+		return ip, redisPort, nil
+	}
+	pods, err := k8SService.GetStatefulSetPods(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		return "", "", err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.PodIP != ip {
+			continue
+		}
+		svc, err := k8SService.GetService(rf.Namespace, pod.Name)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				break
+			}
+			return "", "", err
+		}
+		if host, port, ok := getExposedAddress(svc, pod); ok {
+			return host, port, nil
+		}
+		break
+	}
+	return ip, redisPort, nil
+}
+
+// getSentinelAddress returns the address of the Service exposing the sentinel pod of the given IP, if any
+func getSentinelAddress(k8SService k8s.Services, rf *middlev1alpha1.RedisFailover, ip string) (string, string, bool, error) {
+	pods, err := k8SService.GetDeploymentPods(rf.Namespace, util2.GetSentinelName(rf))
+	if err != nil {
+		return "", "", false, err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.PodIP != ip {
+			continue
+		}
+		index, err := strconv.Atoi(pod.Labels[SentinelIndexLabel])
+		if err != nil {
+			return "", "", false, nil
+		}
+		svc, err := k8SService.GetService(rf.Namespace, util2.GetSentinelExposeName(rf, index))
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return "", "", false, nil
+			}
+			return "", "", false, err
+		}
+		host, port, ok := getExposedAddress(svc, pod)
+		return host, port, ok, nil
+	}
+	return "", "", false, nil
+}
@@ -5,20 +5,31 @@ import (
 	"github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
 	util2 "github.com/DevineLiu/redis-operator/controllers/util"
 	v1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"strconv"
 	"strings"
 )
 
 const (
+	redisConfigurationVolumeName         = "redis-config"
 	redisShutdownConfigurationVolumeName = "redis-shutdown-config"
 	redisStorageVolumeName               = "redis-data"
 	exporterContainerName                = "redis-exporter"
 	graceTime                            = 30
 	redisPasswordEnv                     = "REDIS_PASSWORD"
+	restoreBackupVolumeName              = "restore-backup"
+	restoreBackupMountPath               = "/backup"
+	restoreStagingDir                    = "/data/restore"
+	restoreBackoffLimit                  = 1
+	backupEncryptionKeyEnv               = "BACKUP_ENCRYPTION_KEY"
+	// TemplateHashAnnotation records the hash of the pod template the operator generated for a
+	// StatefulSet or Deployment, the workload is rolled out again when it changes
+	TemplateHashAnnotation = "middle.alauda.cn/template-hash"
 )
 
 func generateRedisService(rf *v1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) *corev1.Service {
@@ -50,30 +61,58 @@ func generateRedisService(rf *v1alpha1.RedisFailover, labels map[string]string,
 	}
 }
 
-func generateRedisNodePortService(rf *v1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) *corev1.Service {
-	namespace := rf.Namespace
+// generateRedisNodePortService builds the Service exposing the redis pod of the given ordinal outside the cluster
+func generateRedisNodePortService(rf *v1alpha1.RedisFailover, ordinal int, labels map[string]string, ownerRefs []metav1.OwnerReference) *corev1.Service {
+	name := util2.GetRedisExposeName(rf, ordinal)
+	selector := util2.MergeMap(generateSelectorLabels(util2.RedisRoleName, rf.Name), map[string]string{
+		v1.StatefulSetPodNameLabel: name,
+	})
+	var nodePort int32
+	if ordinal < len(rf.Spec.Expose.RedisNodePorts) {
+		nodePort = rf.Spec.Expose.RedisNodePorts[ordinal]
+	}
+	return generateExposeService(rf, name, util2.RedisRoleName, 6379, nodePort, selector, labels, ownerRefs)
+}
 
-	labels = util2.MergeMap(labels, generateSelectorLabels(util2.RedisRoleName, rf.Name))
-	redisTargetPort := intstr.FromInt(6379)
+// generateSentinelNodePortService builds the Service exposing the sentinel of the given index outside the cluster
+func generateSentinelNodePortService(rf *v1alpha1.RedisFailover, index int, labels map[string]string, ownerRefs []metav1.OwnerReference) *corev1.Service {
+	selector := util2.MergeMap(generateSelectorLabels(util2.SentinelRoleName, rf.Name), map[string]string{
+		SentinelIndexLabel: strconv.Itoa(index),
+	})
+	var nodePort int32
+	if index < len(rf.Spec.Expose.SentinelNodePorts) {
+		nodePort = rf.Spec.Expose.SentinelNodePorts[index]
+	}
+	return generateExposeService(rf, util2.GetSentinelExposeName(rf, index), util2.SentinelRoleName, 26379, nodePort, selector, labels, ownerRefs)
+}
+
+func generateExposeService(rf *v1alpha1.RedisFailover, name string, component string, port int32, nodePort int32,
+	selector map[string]string, labels map[string]string, ownerRefs []metav1.OwnerReference) *corev1.Service {
+	labels = util2.MergeMap(labels, generateSelectorLabels(component, rf.Name), map[string]string{
+		ExposeLabel: component,
+	})
 	return &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:            util2.GetRedisNodePortSvc(rf),
-			Namespace:       namespace,
+			Name:            name,
+			Namespace:       rf.Namespace,
 			Labels:          labels,
+			Annotations:     rf.Spec.Expose.Annotations,
 			OwnerReferences: ownerRefs,
 		},
 		Spec: corev1.ServiceSpec{
-			Type:      corev1.ServiceTypeNodePort,
-			ClusterIP: corev1.ClusterIPNone,
+			Type: rf.Spec.Expose.Type,
 			Ports: []corev1.ServicePort{
 				{
-					Port:       6379,
+					Port:       port,
 					Protocol:   corev1.ProtocolTCP,
-					Name:       "redis",
-					TargetPort: redisTargetPort,
+					Name:       component,
+					TargetPort: intstr.FromInt(int(port)),
+					NodePort:   nodePort,
 				},
 			},
-			Selector: labels,
+			Selector: selector,
+			// the announced address has to reach the pod the Service is named after
+			ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
 		},
 	}
 }
@@ -130,10 +169,7 @@ func generateSentinelConfigMap(rf *v1alpha1.RedisFailover, labels map[string]str
 	name := util2.GetSentinelName(rf)
 	namespace := rf.Namespace
 	labels = util2.MergeMap(labels, generateSelectorLabels(util2.RedisRoleName, rf.Name))
-	sentinelConfigContent := `sentinel monitor mymaster 127.0.0.1 6379 2
-sentinel down-after-milliseconds mymaster 1000
-sentinel failover-timeout mymaster 3000
-sentinel parallel-syncs mymaster 2`
+	sentinelConfigContent := renderSentinelConfig(rf)
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            name,
@@ -151,17 +187,18 @@ func generateSentinelReadinessProbeConfigMap(rf *v1alpha1.RedisFailover, labels
 	name := util2.GetSentinelReadinessConfigmap(rf)
 	namespace := rf.Namespace
 	labels = util2.MergeMap(labels, generateSelectorLabels(util2.RedisRoleName, rf.Name))
-	checkContent := `#!/usr/bin/env sh
+	// the sentinels are ready once they see the master of their own instance, those sharing them don't count
+	checkContent := fmt.Sprintf(`#!/usr/bin/env sh
 set -eou pipefail
 redis-cli -h $(hostname) -p 26379 ping
-slaves=$(redis-cli -h $(hostname) -p 26379 info sentinel|grep master0| grep -Eo 'slaves=[0-9]+' | awk -F= '{print $2}')
-status=$(redis-cli -h $(hostname) -p 26379 info sentinel|grep master0| grep -Eo 'status=\w+' | awk -F= '{print $2}')
+slaves=$(redis-cli -h $(hostname) -p 26379 info sentinel|grep 'name=%[1]s,'| grep -Eo 'slaves=[0-9]+' | awk -F= '{print $2}')
+status=$(redis-cli -h $(hostname) -p 26379 info sentinel|grep 'name=%[1]s,'| grep -Eo 'status=\w+' | awk -F= '{print $2}')
 if [ "$status" != "ok" ]; then 
     exit 1
 fi
 if [ $slaves -le 1 ]; then
 	exit 1
-fi`
+fi`, util2.GetSentinelMasterName(rf))
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            name,
@@ -180,25 +217,32 @@ func generateRedisShutdownConfigMap(rf *v1alpha1.RedisFailover, labels map[strin
 	name := util2.GetRedisShutdownConfigMapName(rf)
 	namespace := rf.Namespace
 	labels = util2.MergeMap(labels, generateSelectorLabels(util2.RedisRoleName, rf.Name))
-	envSentinelHost := fmt.Sprintf("REDIS_SENTINEL_%s_SERVICE_HOST", strings.ToUpper(rf.Name))
-	envSentinelPort := fmt.Sprintf("REDIS_SENTINEL_%s_SERVICE_PORT_SENTINEL", strings.ToUpper(rf.Name))
+	// the service environment variables of the Service of the sentinels, those of Sentinel.Shared when shared
+	sentinelOwner := rf.Name
+	if rf.Spec.Sentinel.Shared != "" {
+		sentinelOwner = rf.Spec.Sentinel.Shared
+	}
+	sentinelOwner = strings.ToUpper(strings.ReplaceAll(sentinelOwner, "-", "_"))
+	envSentinelHost := fmt.Sprintf("REDIS_SENTINEL_%s_SERVICE_HOST", sentinelOwner)
+	envSentinelPort := fmt.Sprintf("REDIS_SENTINEL_%s_SERVICE_PORT_SENTINEL", sentinelOwner)
+	masterName := util2.GetSentinelMasterName(rf)
 	shutdownContent := fmt.Sprintf(`#!/usr/bin/env sh
 master=""
 response_code=""
 while [ "$master" = "" ]; do
 	echo "Asking sentinel who is master..."
-	master=$(redis-cli -h ${%s} -p ${%s} --csv SENTINEL get-master-addr-by-name mymaster | tr ',' ' ' | tr -d '\"' |cut -d' ' -f1)
+	master=$(redis-cli -h ${%s} -p ${%s} --csv SENTINEL get-master-addr-by-name %s | tr ',' ' ' | tr -d '\"' |cut -d' ' -f1)
 	sleep 1
 done
 echo "Master is $master, doing redis save..."
-redis-cli SAVE
+redis-cli %s
 if [ $master = $(hostname -i) ]; then
 	while [ ! "$response_code" = "OK" ]; do
-  		response_code=$(redis-cli -h ${%s} -p ${%s} SENTINEL failover mymaster)
+  		response_code=$(redis-cli -h ${%s} -p ${%s} SENTINEL failover %s)
 		echo "after failover with code $response_code"
 		sleep 1
 	done
-fi`, envSentinelHost, envSentinelPort, envSentinelHost, envSentinelPort)
+fi`, envSentinelHost, envSentinelPort, masterName, getRedisCommandName(rf, "save"), envSentinelHost, envSentinelPort, masterName)
 
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -236,7 +280,7 @@ func generateSentinelDeployment(rf *v1alpha1.RedisFailover, labels map[string]st
 	labels = util2.MergeMap(labels, generateSelectorLabels(util2.SentinelRoleName, rf.Name))
 	sentinelCommand := getSentinelCommand(rf)
 
-	return &v1.Deployment{
+	deploy := &v1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            name,
 			Namespace:       namespace,
@@ -266,7 +310,7 @@ func generateSentinelDeployment(rf *v1alpha1.RedisFailover, labels map[string]st
 						{
 							Name:            "sentinel-config-copy",
 							Image:           rf.Spec.Sentinel.Image,
-							ImagePullPolicy: pullPolicy(rf.Spec.Sentinel.ImagePullPolicy),
+							ImagePullPolicy: util2.PullPolicy(rf.Spec.Sentinel.ImagePullPolicy),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      "sentinel-config",
@@ -298,7 +342,7 @@ func generateSentinelDeployment(rf *v1alpha1.RedisFailover, labels map[string]st
 						{
 							Name:            "sentinel",
 							Image:           rf.Spec.Sentinel.Image,
-							ImagePullPolicy: pullPolicy(rf.Spec.Sentinel.ImagePullPolicy),
+							ImagePullPolicy: util2.PullPolicy(rf.Spec.Sentinel.ImagePullPolicy),
 							Ports: []corev1.ContainerPort{
 								{
 									Name:          "sentinel",
@@ -379,8 +423,18 @@ func generateSentinelDeployment(rf *v1alpha1.RedisFailover, labels map[string]st
 			},
 		},
 	}
+	if len(getSentinelStaticConfig(rf)) > 0 {
+		if hash, err := getSentinelStaticConfigHash(rf); err == nil {
+			deploy.Spec.Template.Annotations = util2.MergeMap(deploy.Spec.Template.Annotations, map[string]string{
+				SentinelConfigHashAnnotation: hash,
+			})
+		}
+	}
+	setTemplateHash(&deploy.ObjectMeta, deploy.Spec.Template)
+	return deploy
 }
 
+
 func generateRedisStatefulSet(rf *v1alpha1.RedisFailover, labels map[string]string,
 	ownerRefs []metav1.OwnerReference) *v1.StatefulSet {
 	name := util2.GetRedisName(rf)
@@ -394,10 +448,9 @@ func generateRedisStatefulSet(rf *v1alpha1.RedisFailover, labels map[string]stri
 
 	probeArg := "redis-cli -h $(hostname)"
 	if spec.Auth.SecretPath != "" {
-		probeArg = fmt.Sprintf("%s -a ${%s} ping", probeArg,redisPasswordEnv)
-	} else {
-		probeArg = fmt.Sprintf("%s ping", probeArg)
+		probeArg = fmt.Sprintf("%s -a ${%s}", probeArg, redisPasswordEnv)
 	}
+	probeArg = fmt.Sprintf("%s %s", probeArg, getRedisCommandName(rf, "ping"))
 
 	ss := &v1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
@@ -409,8 +462,9 @@ func generateRedisStatefulSet(rf *v1alpha1.RedisFailover, labels map[string]stri
 		Spec: v1.StatefulSetSpec{
 			ServiceName: name,
 			Replicas:    &spec.Redis.Replicas,
+			// the operator replaces the pods itself, the master last, see RedisFailoverHandler.UpgradeRedis
 			UpdateStrategy: v1.StatefulSetUpdateStrategy{
-				Type: "RollingUpdate",
+				Type: v1.OnDeleteStatefulSetStrategyType,
 			},
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
@@ -430,7 +484,7 @@ func generateRedisStatefulSet(rf *v1alpha1.RedisFailover, labels map[string]stri
 						{
 							Name:            "redis",
 							Image:           rf.Spec.Redis.Image,
-							ImagePullPolicy: pullPolicy(rf.Spec.Redis.ImagePullPolicy),
+							ImagePullPolicy: util2.PullPolicy(rf.Spec.Redis.ImagePullPolicy),
 							Ports: []corev1.ContainerPort{
 								{
 									Name:          "redis",
@@ -512,14 +566,219 @@ func generateRedisStatefulSet(rf *v1alpha1.RedisFailover, labels map[string]stri
 		ss.Spec.Template.Spec.Containers = append(ss.Spec.Template.Spec.Containers, exporter)
 	}
 
+	if hasRedisRestoreSource(rf) {
+		ss.Spec.Template.Spec.InitContainers = append(ss.Spec.Template.Spec.InitContainers, createRedisRestoreContainer(rf))
+	}
+
+	setTemplateHash(&ss.ObjectMeta, ss.Spec.Template)
 	return ss
 }
 
+// setTemplateHash annotates the workload with the hash of its pod template
+func setTemplateHash(meta *metav1.ObjectMeta, template corev1.PodTemplateSpec) {
+	hash, err := util2.GenerateObjectHash(template)
+	if err != nil {
+		return
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[TemplateHashAnnotation] = hash
+}
+
+// createRedisRestoreContainer moves the RDB staged by the restore Job into place on the first pod,
+// before redis starts. It is a no-op on the other pods and once the staged file has been consumed.
+func createRedisRestoreContainer(rf *v1alpha1.RedisFailover) corev1.Container {
+	restoreContent := fmt.Sprintf(`case "$(hostname)" in
+  *-0) ;;
+  *) exit 0 ;;
+esac
+if [ -f %[1]s/%[2]s ]; then
+  mv %[1]s/%[2]s /data/%[2]s
+fi`, restoreStagingDir, util2.BackupFileName)
+
+	return corev1.Container{
+		Name:            "restore",
+		Image:           getRedisRestoreImage(rf),
+		ImagePullPolicy: util2.PullPolicy(rf.Spec.Redis.Restore.ImagePullPolicy),
+		Command:         []string{"sh", "-c", restoreContent},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      getRedisDataVolumeName(rf),
+				MountPath: "/data",
+			},
+		},
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("10m"),
+				corev1.ResourceMemory: resource.MustParse("32Mi"),
+			},
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("10m"),
+				corev1.ResourceMemory: resource.MustParse("32Mi"),
+			},
+		},
+	}
+}
+
+// generateRedisRestoreDataPersistentVolumeClaim builds the data claim of the first redis pod the way
+// the StatefulSet would, so the restore Job can seed it before the StatefulSet exists
+func generateRedisRestoreDataPersistentVolumeClaim(rf *v1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) *corev1.PersistentVolumeClaim {
+	pvc := rf.Spec.Redis.Storage.PersistentVolumeClaim.DeepCopy()
+	pvc.Name = getRedisRestoreDataPersistentVolumeClaimName(rf)
+	pvc.Namespace = rf.Namespace
+	pvc.Labels = util2.MergeMap(pvc.Labels, labels, generateSelectorLabels(util2.RedisRoleName, rf.Name))
+	if !rf.Spec.Redis.Storage.KeepAfterDeletion {
+		pvc.OwnerReferences = ownerRefs
+	}
+	return pvc
+}
+
+// generateRedisRestoreJob builds the Job staging the RDB of the backup into the data volume of the
+// first redis pod, copied from the backup volume or downloaded from S3, then decrypted when the backup
+// is encrypted. The file is written under a temporary name so the init container never sees a partial copy.
+func generateRedisRestoreJob(rf *v1alpha1.RedisFailover, backup *v1alpha1.RedisBackup, labels map[string]string, ownerRefs []metav1.OwnerReference) *batchv1.Job {
+	backoffLimit := int32(restoreBackoffLimit)
+	target := fmt.Sprintf("%s/%s", restoreStagingDir, util2.BackupFileName)
+	fetched := target + ".tmp"
+	if backup.Spec.Encryption != nil {
+		fetched = fmt.Sprintf("%s/%s", restoreStagingDir, util2.GetBackupFileName(backup))
+	}
+	copyCommand := fmt.Sprintf("cp %s/%s %s", restoreBackupMountPath, backup.Status.Path, fetched)
+	if s3 := backup.Spec.Storage.S3; s3 != nil {
+		copyCommand = fmt.Sprintf("aws --endpoint-url %s s3 cp s3://%s/%s %s", s3.Endpoint, s3.Bucket, backup.Status.Path, fetched)
+	}
+	restoreContent := fmt.Sprintf(`set -e
+mkdir -p %s
+%s`, restoreStagingDir, copyCommand)
+	if backup.Spec.Encryption == nil {
+		restoreContent = fmt.Sprintf("%s\nmv %s %s", restoreContent, fetched, target)
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            util2.GetRedisRestoreJobName(rf),
+			Namespace:       rf.Namespace,
+			Labels:          labels,
+			OwnerReferences: ownerRefs,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					ImagePullSecrets: rf.Spec.Redis.ImagePullSecrets,
+					SecurityContext:  getSecurityContext(rf.Spec.Redis.SecurityContext),
+					Containers: []corev1.Container{
+						{
+							Name:            "restore",
+							Image:           getRedisRestoreImage(rf),
+							ImagePullPolicy: util2.PullPolicy(rf.Spec.Redis.Restore.ImagePullPolicy),
+							Command:         []string{"sh", "-c", restoreContent},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      restoreBackupVolumeName,
+									MountPath: restoreBackupMountPath,
+									ReadOnly:  true,
+								},
+								{
+									Name:      getRedisDataVolumeName(rf),
+									MountPath: "/data",
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: restoreBackupVolumeName,
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: backup.Status.PersistentVolumeClaim,
+									ReadOnly:  true,
+								},
+							},
+						},
+						{
+							Name: getRedisDataVolumeName(rf),
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: getRedisRestoreDataPersistentVolumeClaimName(rf),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if s3 := backup.Spec.Storage.S3; s3 != nil {
+		podSpec := &job.Spec.Template.Spec
+		podSpec.Volumes = podSpec.Volumes[1:]
+		podSpec.Containers[0].VolumeMounts = podSpec.Containers[0].VolumeMounts[1:]
+		podSpec.Containers[0].Image = s3.Image
+		podSpec.Containers[0].ImagePullPolicy = util2.PullPolicy(s3.ImagePullPolicy)
+		podSpec.Containers[0].Env = util2.GenerateS3Env(s3)
+	}
+
+	if encryption := backup.Spec.Encryption; encryption != nil {
+		// the backup is fetched in an init container and decrypted by the main one
+		podSpec := &job.Spec.Template.Spec
+		podSpec.InitContainers = podSpec.Containers
+		decryptContent := fmt.Sprintf(`set -e
+openssl enc -d -aes-256-cbc -pbkdf2 -in %[1]s -out %[2]s.tmp -pass env:%[3]s
+rm %[1]s
+mv %[2]s.tmp %[2]s`, fetched, target, backupEncryptionKeyEnv)
+		podSpec.Containers = []corev1.Container{
+			{
+				Name:            "decrypt",
+				Image:           encryption.Image,
+				ImagePullPolicy: util2.PullPolicy(encryption.ImagePullPolicy),
+				Command:         []string{"sh", "-c", decryptContent},
+				Env: []corev1.EnvVar{
+					{
+						Name: backupEncryptionKeyEnv,
+						ValueFrom: &corev1.EnvVarSource{
+							SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{
+									Name: encryption.SecretName,
+								},
+								Key: backup.Status.EncryptionKeyID,
+							},
+						},
+					},
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{
+						Name:      getRedisDataVolumeName(rf),
+						MountPath: "/data",
+					},
+				},
+			},
+		}
+	}
+	return job
+}
+
+func getRedisRestoreDataPersistentVolumeClaimName(rf *v1alpha1.RedisFailover) string {
+	return fmt.Sprintf("%s-%s-0", rf.Spec.Redis.Storage.PersistentVolumeClaim.Name, util2.GetRedisName(rf))
+}
+
+func getRedisRestoreImage(rf *v1alpha1.RedisFailover) string {
+	if rf.Spec.Redis.Restore.Image != "" {
+		return rf.Spec.Redis.Restore.Image
+	}
+	return rf.Spec.Redis.Image
+}
+
 func createRedisExporterContainer(rf *v1alpha1.RedisFailover) corev1.Container {
 	container := corev1.Container{
 		Name:            exporterContainerName,
 		Image:           rf.Spec.Redis.Exporter.Image,
-		ImagePullPolicy: pullPolicy(rf.Spec.Redis.Exporter.ImagePullPolicy),
+		ImagePullPolicy: util2.PullPolicy(rf.Spec.Redis.Exporter.ImagePullPolicy),
 		Env: []corev1.EnvVar{
 			{
 				Name: "REDIS_ALIAS",
@@ -548,6 +807,13 @@ func createRedisExporterContainer(rf *v1alpha1.RedisFailover) corev1.Container {
 			},
 		},
 	}
+	// redis_exporter reads the configuration of redis with CONFIG
+	if config := getRedisCommandName(rf, "config"); config != "config" {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "REDIS_EXPORTER_CONFIG_COMMAND",
+			Value: config,
+		})
+	}
 	if rf.Spec.Auth.SecretPath != "" {
 		container.Env = append(container.Env, corev1.EnvVar{
 			Name: redisPasswordEnv,
@@ -565,12 +831,13 @@ func createRedisExporterContainer(rf *v1alpha1.RedisFailover) corev1.Container {
 }
 
 func getRedisCommand(rf *v1alpha1.RedisFailover) []string {
+	if len(rf.Spec.Redis.Command) > 0 {
+		return rf.Spec.Redis.Command
+	}
 	cmds := []string{
 		"redis-server",
+		fmt.Sprintf("/redis/%s", util2.RedisConfigFileName),
 		"--slaveof 127.0.0.1 6379",
-		"--tcp-keepalive 60",
-		"--save 900 1",
-		"--save 300 10",
 	}
 	return cmds
 }
@@ -606,13 +873,6 @@ func getSecurityContext(secctx *corev1.PodSecurityContext) *corev1.PodSecurityCo
 	return nil
 }
 
-func pullPolicy(specPolicy corev1.PullPolicy) corev1.PullPolicy {
-	if specPolicy == "" {
-		return corev1.PullAlways
-	}
-	return specPolicy
-}
-
 func getSentinelCommand(rf *v1alpha1.RedisFailover) []string {
 	if len(rf.Spec.Sentinel.Command) > 0 {
 		return rf.Spec.Sentinel.Command
@@ -626,10 +886,10 @@ func getSentinelCommand(rf *v1alpha1.RedisFailover) []string {
 
 func getRedisVolumeMounts(rf *v1alpha1.RedisFailover) []corev1.VolumeMount {
 	volumeMounts := []corev1.VolumeMount{
-		//{
-		//	Name:      redisConfigurationVolumeName,
-		//	MountPath: "/redis",
-		//},
+		{
+			Name:      redisConfigurationVolumeName,
+			MountPath: "/redis",
+		},
 		{
 			Name:      "redis-shutdown-config",
 			MountPath: "/redis-shutdown",
@@ -659,6 +919,16 @@ func getRedisVolumes(rf *v1alpha1.RedisFailover) []corev1.Volume {
 
 	executeMode := int32(0744)
 	volumes := []corev1.Volume{
+		{
+			Name: redisConfigurationVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: util2.GetRedisConfigMapName(rf),
+					},
+				},
+			},
+		},
 		{
 			Name: redisShutdownConfigurationVolumeName,
 			VolumeSource: corev1.VolumeSource{
@@ -2,6 +2,7 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
 	"github.com/DevineLiu/redis-operator/controllers/middle/client/k8s"
 	"github.com/DevineLiu/redis-operator/controllers/middle/client/redis"
@@ -15,12 +16,19 @@ import (
 
 type RedisFailoverHeal interface {
 	MakeMaster(ip string, auth *util2.AuthConfig) error
-	SetOldestAsMaster(rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error
+	SetMostUpToDateAsMaster(rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error
+	SetFirstAsMaster(rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error
 	SetMasterOnAll(masterIP string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error
-	NewSentinelMonitor(ip string, monitor string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error
+	NewSentinelMonitor(ip string, monitor string, monitorPort string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error
 	RestoreSentinel(ip string, auth *util2.AuthConfig) error
+	RemoveSentinelMonitor(ip string, auth *util2.AuthConfig) error
 	SetSentinelCustomConfig(ip string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error
 	SetRedisCustomConfig(ip string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error
+	SentinelFailover(sentinel string, auth *util2.AuthConfig) error
+	SetReplicaPriority(ip string, priority int, auth *util2.AuthConfig) error
+	SetRedisAnnounceAddress(ip string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error
+	SetSentinelAnnounceAddress(ip string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error
+	SetSentinelCommandRenames(ip string, renames map[string]string, auth *util2.AuthConfig) error
 }
 
 type RedisFailoverHealer struct {
@@ -37,11 +45,44 @@ func NewRedisFailoverHealer(k8SService k8s.Services, log logr.Logger, status cli
 	}
 }
 
+// SentinelFailover has the given sentinel move the master onto one of its replicas
+func (r RedisFailoverHealer) SentinelFailover(sentinel string, auth *util2.AuthConfig) error {
+	return r.RedisClient.SentinelFailover(sentinel, auth)
+}
+
+// SetReplicaPriority sets the priority of the given redis in the election of a new master, 0 excludes it
+func (r RedisFailoverHealer) SetReplicaPriority(ip string, priority int, auth *util2.AuthConfig) error {
+	return r.RedisClient.SetCustomRedisConfig(ip, map[string]string{"slave-priority": strconv.Itoa(priority)}, auth)
+}
+
+// SetRedisAnnounceAddress has the given redis announce the address of the Service exposing it, once allocated
+func (r RedisFailoverHealer) SetRedisAnnounceAddress(ip string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
+	host, port, err := getRedisAddress(r.K8SService, rf, ip)
+	if err != nil || host == ip {
+		return err
+	}
+	return r.RedisClient.SetCustomRedisConfig(ip, map[string]string{
+		"slave-announce-ip":   host,
+		"slave-announce-port": port,
+	}, auth)
+}
+
+// SetSentinelAnnounceAddress has the given sentinel announce the address of the Service exposing it, once allocated
+func (r RedisFailoverHealer) SetSentinelAnnounceAddress(ip string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
+	host, port, ok, err := getSentinelAddress(r.K8SService, rf, ip)
+	if err != nil || !ok {
+		return err
+	}
+	return r.RedisClient.SetSentinelAnnounceAddress(ip, host, port, auth)
+}
+
 func (r RedisFailoverHealer) MakeMaster(ip string, auth *util2.AuthConfig) error {
 	return r.RedisClient.MakeMaster(ip, auth)
 }
 
-func (r RedisFailoverHealer) SetOldestAsMaster(rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
+// SetMostUpToDateAsMaster promotes the redis pod with the highest replication offset and makes it the
+// master of the others. Pods with a slave-priority of 0 are never promoted, pod age only breaks ties.
+func (r RedisFailoverHealer) SetMostUpToDateAsMaster(rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
 	ssp, err := r.K8SService.GetStatefulSetPods(rf.Namespace, util2.GetRedisName(rf))
 	if err != nil {
 		return err
@@ -56,19 +97,47 @@ func (r RedisFailoverHealer) SetOldestAsMaster(rf *middlev1alpha1.RedisFailover,
 	})
 
 	newMasterIP := ""
+	var newMasterOffset int64
 	for _, pod := range ssp.Items {
-		if newMasterIP == "" {
+		if pod.Status.PodIP == "" {
+			continue
+		}
+		replication, err := r.RedisClient.GetReplicationInfo(pod.Status.PodIP, auth)
+		if err != nil {
+			r.Logger.Error(err, "redis pod can't be elected master", "pod", pod.Name)
+			continue
+		}
+		if replication.Priority == 0 {
+			continue
+		}
+		// the sort keeps the oldest pod on equal offsets
+		if newMasterIP == "" || replication.Offset > newMasterOffset {
+			newMasterIP, newMasterOffset = pod.Status.PodIP, replication.Offset
+		}
+	}
+	if newMasterIP == "" {
+		return errors.New("no redis pod can be elected master")
+	}
+	return r.SetMasterOnAll(newMasterIP, rf, auth)
+}
+
+// SetFirstAsMaster makes the pod of the first ordinal the master of the others, it is the pod seeded by a restore
+func (r RedisFailoverHealer) SetFirstAsMaster(rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
+	ssp, err := r.K8SService.GetStatefulSetPods(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		return err
+	}
+	firstName := fmt.Sprintf("%s-0", util2.GetRedisName(rf))
+	newMasterIP := ""
+	for _, pod := range ssp.Items {
+		if pod.Name == firstName {
 			newMasterIP = pod.Status.PodIP
-			if err := r.RedisClient.MakeMaster(newMasterIP, auth); err != nil {
-				return err
-			}
-		} else {
-			if err := r.RedisClient.MakeSlaveOf(pod.Status.PodIP, newMasterIP, auth); err != nil {
-				return err
-			}
 		}
 	}
-	return nil
+	if newMasterIP == "" {
+		return fmt.Errorf("redis pod %s not found", firstName)
+	}
+	return r.SetMasterOnAll(newMasterIP, rf, auth)
 }
 
 func (r RedisFailoverHealer) SetMasterOnAll(masterIP string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
@@ -76,13 +145,17 @@ func (r RedisFailoverHealer) SetMasterOnAll(masterIP string, rf *middlev1alpha1.
 	if err != nil {
 		return err
 	}
+	masterHost, masterPort, err := getRedisAddress(r.K8SService, rf, masterIP)
+	if err != nil {
+		return err
+	}
 	for _, pod := range ssp.Items {
 		if pod.Status.PodIP == masterIP {
 			if err := r.RedisClient.MakeMaster(masterIP, auth); err != nil {
 				return err
 			}
 		} else {
-			if err := r.RedisClient.MakeSlaveOf(pod.Status.PodIP, masterIP, auth); err != nil {
+			if err := r.RedisClient.MakeSlaveOf(pod.Status.PodIP, masterHost, masterPort, auth); err != nil {
 				return err
 			}
 		}
@@ -90,25 +163,39 @@ func (r RedisFailoverHealer) SetMasterOnAll(masterIP string, rf *middlev1alpha1.
 	return nil
 }
 
-func (r RedisFailoverHealer) NewSentinelMonitor(ip string, monitor string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
-	quorum := strconv.Itoa(int(rf.Spec.Sentinel.Replicas/2 + 1))
-	return r.RedisClient.MonitorRedis(ip, monitor, quorum, auth)
+func (r RedisFailoverHealer) NewSentinelMonitor(ip string, monitor string, monitorPort string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
+	quorum := strconv.Itoa(int(getSentinelQuorum(rf)))
+	if err := r.RedisClient.MonitorRedis(ip, monitor, monitorPort, quorum, auth); err != nil {
+		return err
+	}
+	// monitoring the master again dropped its settings
+	return r.SetSentinelCustomConfig(ip, rf, auth)
+}
+
+// SetSentinelCommandRenames tells the sentinel the names redis knows the commands by, a command renamed to itself drops its rename
+func (r RedisFailoverHealer) SetSentinelCommandRenames(ip string, renames map[string]string, auth *util2.AuthConfig) error {
+	return r.RedisClient.SetSentinelCommandRenames(ip, renames, auth)
+}
+
+// RemoveSentinelMonitor has the sentinel stop monitoring the master of the instance
+func (r RedisFailoverHealer) RemoveSentinelMonitor(ip string, auth *util2.AuthConfig) error {
+	return r.RedisClient.RemoveMonitor(ip, auth)
 }
 
 func (r RedisFailoverHealer) RestoreSentinel(ip string, auth *util2.AuthConfig) error {
 	return r.RedisClient.ResetSentinel(ip, auth)
 }
 
+// SetSentinelCustomConfig sets the tuning fields of SentinelSettings and its CustomConfig on the monitored master
 func (r RedisFailoverHealer) SetSentinelCustomConfig(ip string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
-	if len(rf.Spec.Sentinel.CustomConfig) == 0 {
-		return nil
-	}
-	return r.RedisClient.SetCustomSentinelConfig(ip, rf.Spec.Sentinel.CustomConfig, auth)
+	return r.RedisClient.SetCustomSentinelConfig(ip, getSentinelConfig(rf), auth)
 }
 
 func (r RedisFailoverHealer) SetRedisCustomConfig(ip string, rf *middlev1alpha1.RedisFailover, auth *util2.AuthConfig) error {
-	if len(rf.Spec.Redis.CustomConfig) == 0 && len(auth.Password) == 0 {
-		return nil
+	// the static settings reach the pods through redis.conf, with a restart
+	config, err := loadRedisConfig(r.K8SService, rf)
+	if err != nil {
+		return err
 	}
-	return r.RedisClient.SetCustomRedisConfig(ip, rf.Spec.Redis.CustomConfig, auth)
+	return r.RedisClient.SetCustomRedisConfig(ip, getRedisLiveConfig(rf, config), auth)
 }
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"testing"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/client/redis"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func (c *fakeRedisClient) MakeMaster(ip string, auth *util2.AuthConfig) error {
+	c.master = ip
+	return nil
+}
+
+func (c *fakeRedisClient) MakeSlaveOf(ip string, masterIP string, masterPort string, auth *util2.AuthConfig) error {
+	return nil
+}
+
+// newRedisObjects returns the StatefulSet of the instance and a running pod of it per IP, created a minute apart
+func newRedisObjects(rf *middlev1alpha1.RedisFailover, ips ...string) []runtime.Object {
+	return newWorkloadObjects(rf, util2.GetRedisName(rf), util2.RedisRoleName, &appsv1.StatefulSet{}, ips)
+}
+
+func TestSetMostUpToDateAsMaster(t *testing.T) {
+	type pod struct {
+		ip       string
+		offset   int64
+		priority int
+		// unreachable pods answer no INFO replication
+		unreachable bool
+	}
+	tests := []struct {
+		name string
+		// pods from the oldest to the newest
+		pods       []pod
+		wantMaster string
+		wantErr    bool
+	}{
+		{
+			name:       "highest offset",
+			pods:       []pod{{ip: "10.0.0.1", offset: 10, priority: 100}, {ip: "10.0.0.2", offset: 30, priority: 100}, {ip: "10.0.0.3", offset: 20, priority: 100}},
+			wantMaster: "10.0.0.2",
+		},
+		{
+			name:       "priority 0 never promoted",
+			pods:       []pod{{ip: "10.0.0.1", offset: 10, priority: 100}, {ip: "10.0.0.2", offset: 30, priority: 0}},
+			wantMaster: "10.0.0.1",
+		},
+		{
+			name:       "oldest pod on equal offsets",
+			pods:       []pod{{ip: "10.0.0.3", offset: 20, priority: 100}, {ip: "10.0.0.1", offset: 20, priority: 100}, {ip: "10.0.0.2", offset: 10, priority: 100}},
+			wantMaster: "10.0.0.3",
+		},
+		{
+			name:       "lowest non zero priority still promoted",
+			pods:       []pod{{ip: "10.0.0.1", offset: 10, priority: 100}, {ip: "10.0.0.2", offset: 20, priority: 1}},
+			wantMaster: "10.0.0.2",
+		},
+		{
+			name:       "unreachable pods skipped",
+			pods:       []pod{{ip: "10.0.0.1", offset: 10, priority: 100}, {ip: "10.0.0.2", offset: 30, priority: 100, unreachable: true}},
+			wantMaster: "10.0.0.1",
+		},
+		{
+			name:       "pods without IP skipped",
+			pods:       []pod{{ip: "", offset: 30, priority: 100}, {ip: "10.0.0.2", offset: 10, priority: 100}},
+			wantMaster: "10.0.0.2",
+		},
+		{
+			name:    "every pod with priority 0",
+			pods:    []pod{{ip: "10.0.0.1", offset: 10}, {ip: "10.0.0.2", offset: 20}},
+			wantErr: true,
+		},
+		{
+			name:    "no pod",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &middlev1alpha1.RedisFailover{ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default"}}
+			redisClient := &fakeRedisClient{replication: map[string]*redis.ReplicationInfo{}}
+			ips := []string{}
+			for _, p := range tt.pods {
+				ips = append(ips, p.ip)
+				if !p.unreachable {
+					redisClient.replication[p.ip] = &redis.ReplicationInfo{Offset: p.offset, Priority: p.priority}
+				}
+			}
+			healer := RedisFailoverHealer{
+				K8SService:  newFakeServices(t, newRedisObjects(rf, ips...)...),
+				Logger:      logr.Discard(),
+				RedisClient: redisClient,
+			}
+
+			err := healer.SetMostUpToDateAsMaster(rf, &util2.AuthConfig{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetMostUpToDateAsMaster() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if redisClient.master != tt.wantMaster {
+				t.Errorf("SetMostUpToDateAsMaster() promoted %q, want %q", redisClient.master, tt.wantMaster)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"fmt"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/client/k8s"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const BackupCloneLabel = "redis/clone"
+
+// hasRedisRestoreSource reports whether the instance is seeded from a backup, by Restore.BackupName or Source
+func hasRedisRestoreSource(rf *middlev1alpha1.RedisFailover) bool {
+	return rf.Spec.Redis.Restore.BackupName != "" || rf.Spec.Source != nil
+}
+
+// IsRedisRestorePending reports whether the instance still has to be restored from Restore.BackupName or Source
+func IsRedisRestorePending(rf *middlev1alpha1.RedisFailover) bool {
+	return hasRedisRestoreSource(rf) && !rf.Status.IsRestoreDone()
+}
+
+// DescribeRedisRestoreSource names what the instance is restored from
+func DescribeRedisRestoreSource(rf *middlev1alpha1.RedisFailover) string {
+	switch {
+	case rf.Spec.Source != nil && rf.Spec.Source.RedisFailover != "":
+		return fmt.Sprintf("redisfailover %s", rf.Spec.Source.RedisFailover)
+	case rf.Spec.Source != nil:
+		return fmt.Sprintf("backup %s", rf.Spec.Source.Backup)
+	default:
+		return fmt.Sprintf("backup %s", rf.Spec.Redis.Restore.BackupName)
+	}
+}
+
+// EnsureRedisRestore stages the RDB of Restore.BackupName, or of Source, into the data volume of the first
+// redis pod. The restore only seeds a new instance, nothing is done once the redis StatefulSet exists.
+func (r RedisFailoverKubeClient) EnsureRedisRestore(rf *middlev1alpha1.RedisFailover, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
+	if !IsRedisRestorePending(rf) {
+		return nil
+	}
+	if _, err := r.K8SService.GetStatefulSet(rf.Namespace, util2.GetRedisName(rf)); err == nil {
+		return nil
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+
+	backup, err := r.getRedisRestoreBackup(rf, ownerRefs)
+	if err != nil {
+		return err
+	}
+	switch backup.Status.Phase {
+	case middlev1alpha1.RedisBackupPhaseSucceeded:
+	case middlev1alpha1.RedisBackupPhaseFailed:
+		return fmt.Errorf("backup %s/%s failed and cannot be restored", backup.Namespace, backup.Name)
+	default:
+		r.Logger.WithValues("namespace", rf.Namespace, "name", rf.Name).V(2).Info("waiting for backup to succeed", "backup", backup.Name)
+		return nil
+	}
+	if backup.Spec.Encryption != nil && backup.Status.EncryptionKeyID == "" {
+		return fmt.Errorf("backup %s/%s does not record its encryption key", backup.Namespace, backup.Name)
+	}
+	if rf.Spec.Redis.Storage.PersistentVolumeClaim == nil {
+		return fmt.Errorf("restoring backup %s/%s requires redis storage on a persistentVolumeClaim", backup.Namespace, backup.Name)
+	}
+	if backup.Namespace != rf.Namespace {
+		if backup.Spec.Storage.S3 == nil {
+			return fmt.Errorf("backup %s/%s is not on s3 and cannot be restored in another namespace", backup.Namespace, backup.Name)
+		}
+		if backup, err = r.copyRedisRestoreSecrets(rf, backup, labels, ownerRefs); err != nil {
+			return err
+		}
+	}
+
+	pvc := generateRedisRestoreDataPersistentVolumeClaim(rf, labels, ownerRefs)
+	if err := r.K8SService.CreateIfNotExistsPersistentVolumeClaim(rf.Namespace, pvc); err != nil {
+		return err
+	}
+	job := generateRedisRestoreJob(rf, backup, labels, ownerRefs)
+	return r.K8SService.CreateIfNotExistsJob(rf.Namespace, job)
+}
+
+func (r RedisFailoverKubeClient) getRedisRestoreBackup(rf *middlev1alpha1.RedisFailover, ownerRefs []metav1.OwnerReference) (*middlev1alpha1.RedisBackup, error) {
+	source := rf.Spec.Source
+	switch {
+	case source == nil:
+		return r.K8SService.GetRedisBackup(rf.Namespace, rf.Spec.Redis.Restore.BackupName)
+	case source.Backup != "":
+		namespace, name := util2.SplitNamespacedName(source.Backup, rf.Namespace)
+		return r.K8SService.GetRedisBackup(namespace, name)
+	default:
+		return r.ensureRedisCloneBackup(rf, ownerRefs)
+	}
+}
+
+// ensureRedisCloneBackup takes a RedisBackup of Source.RedisFailover, in the namespace of the source
+func (r RedisFailoverKubeClient) ensureRedisCloneBackup(rf *middlev1alpha1.RedisFailover, ownerRefs []metav1.OwnerReference) (*middlev1alpha1.RedisBackup, error) {
+	source := rf.Spec.Source
+	namespace, name := util2.SplitNamespacedName(source.RedisFailover, rf.Namespace)
+	if namespace != rf.Namespace && source.Storage.S3 == nil {
+		return nil, fmt.Errorf("cloning redisfailover %s from another namespace requires source storage on s3", source.RedisFailover)
+	}
+	backup, err := r.K8SService.GetRedisBackup(namespace, util2.GetCloneBackupName(rf))
+	if err == nil {
+		return backup, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if _, err := r.K8SService.GetRedisFailover(namespace, name); err != nil {
+		return nil, err
+	}
+
+	backup = &middlev1alpha1.RedisBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util2.GetCloneBackupName(rf),
+			Namespace: namespace,
+			Labels: map[string]string{
+				"redis/managed-by": "redis-operator",
+				BackupCloneLabel:   fmt.Sprintf("%s%c%s", rf.Namespace, '_', rf.Name),
+			},
+		},
+		Spec: middlev1alpha1.RedisBackupSpec{
+			RedisFailoverName: name,
+			Storage:           source.Storage,
+			Encryption:        source.Encryption,
+		},
+	}
+	// owner references can't cross namespaces, a backup taken in another namespace outlives the clone
+	if namespace == rf.Namespace {
+		backup.OwnerReferences = ownerRefs
+	}
+	if err := r.K8SService.CreateRedisBackup(namespace, backup); err != nil && !errors.IsAlreadyExists(err) {
+		return nil, err
+	}
+	r.Record.Event(rf, corev1.EventTypeNormal, "CloneBackup", fmt.Sprintf("backup %s/%s of redisfailover %s requested", namespace, backup.Name, name))
+	return backup, nil
+}
+
+// copyRedisRestoreSecrets copies the s3 credentials and the encryption key of a backup in another namespace
+// next to the instance, and returns the backup pointing at the copies
+func (r RedisFailoverKubeClient) copyRedisRestoreSecrets(rf *middlev1alpha1.RedisFailover, backup *middlev1alpha1.RedisBackup, labels map[string]string, ownerRefs []metav1.OwnerReference) (*middlev1alpha1.RedisBackup, error) {
+	backup = backup.DeepCopy()
+	s3 := backup.Spec.Storage.S3
+	s3Secret := util2.GetRedisRestoreSecretName(rf, "s3")
+	if err := r.copySecret(rf, backup.Namespace, s3.CredentialsSecret, s3Secret, util2.S3CredentialKeys, labels, ownerRefs); err != nil {
+		return nil, err
+	}
+	s3.CredentialsSecret = s3Secret
+	if encryption := backup.Spec.Encryption; encryption != nil {
+		encryptionSecret := util2.GetRedisRestoreSecretName(rf, "encryption")
+		if err := r.copySecret(rf, backup.Namespace, encryption.SecretName, encryptionSecret, []string{backup.Status.EncryptionKeyID}, labels, ownerRefs); err != nil {
+			return nil, err
+		}
+		encryption.SecretName = encryptionSecret
+	}
+	return backup, nil
+}
+
+// copySecret copies the keys of a Secret of another namespace into copyName, next to the instance
+func (r RedisFailoverKubeClient) copySecret(rf *middlev1alpha1.RedisFailover, namespace, name, copyName string, keys []string, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
+	secret, err := r.K8SService.GetSecret(namespace, name)
+	if err != nil {
+		return err
+	}
+	data := map[string][]byte{}
+	for _, key := range keys {
+		value, ok := secret.Data[key]
+		if !ok {
+			return fmt.Errorf("secret %s/%s has no %s key", namespace, name, key)
+		}
+		data[key] = value
+	}
+	return r.K8SService.CreateOrUpdateSecret(rf.Namespace, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            copyName,
+			Namespace:       rf.Namespace,
+			Labels:          labels,
+			OwnerReferences: ownerRefs,
+		},
+		Type: secret.Type,
+		Data: data,
+	})
+}
+
+// isRedisRestoreStaged reports whether the restore Job copied the RDB into the data volume of the first pod
+func isRedisRestoreStaged(k8SService k8s.Services, rf *middlev1alpha1.RedisFailover) (bool, error) {
+	job, err := k8SService.GetJob(rf.Namespace, util2.GetRedisRestoreJobName(rf))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if job.Spec.BackoffLimit != nil && job.Status.Failed > *job.Spec.BackoffLimit {
+		return false, fmt.Errorf("restore job %s failed", job.Name)
+	}
+	return job.Status.Succeeded > 0, nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEnsureRedisRestoreFromAnotherNamespace(t *testing.T) {
+	s3 := &middlev1alpha1.RedisBackupS3Storage{Endpoint: "http://minio:9000", Bucket: "backups", CredentialsSecret: "s3-credentials"}
+	newBackup := func(storage middlev1alpha1.RedisBackupStorage, encryption *middlev1alpha1.RedisBackupEncryption) *middlev1alpha1.RedisBackup {
+		backup := &middlev1alpha1.RedisBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: "origin-backup", Namespace: "other"},
+			Spec:       middlev1alpha1.RedisBackupSpec{RedisFailoverName: "origin", Storage: storage, Encryption: encryption},
+			Status:     middlev1alpha1.RedisBackupStatus{Phase: middlev1alpha1.RedisBackupPhaseSucceeded, Path: "origin/dump.rdb"},
+		}
+		if encryption != nil {
+			backup.Status.EncryptionKeyID = "key-1"
+		}
+		return backup
+	}
+	secrets := []runtime.Object{
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3-credentials", Namespace: "other"},
+			Data:       map[string][]byte{"AWS_ACCESS_KEY_ID": []byte("id"), "AWS_SECRET_ACCESS_KEY": []byte("secret"), "unrelated": []byte("x")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "backup-keys", Namespace: "other"},
+			Data:       map[string][]byte{"key-1": []byte("k1"), "key-0": []byte("k0")},
+		},
+	}
+	tests := []struct {
+		name   string
+		source *middlev1alpha1.RedisFailoverSource
+		objs   []runtime.Object
+		// wantSecrets are the Secrets the restore Job reads, with the keys copied into them
+		wantSecrets map[string][]string
+		wantErr     bool
+	}{
+		{
+			name:   "backup on s3",
+			source: &middlev1alpha1.RedisFailoverSource{Backup: "other/origin-backup"},
+			objs:   append([]runtime.Object{newBackup(middlev1alpha1.RedisBackupStorage{S3: s3}, nil)}, secrets...),
+			wantSecrets: map[string][]string{
+				"redis-restore-s3-clone": {"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"},
+			},
+		},
+		{
+			name:   "encrypted backup on s3",
+			source: &middlev1alpha1.RedisFailoverSource{Backup: "other/origin-backup"},
+			objs: append([]runtime.Object{newBackup(middlev1alpha1.RedisBackupStorage{S3: s3},
+				&middlev1alpha1.RedisBackupEncryption{SecretName: "backup-keys", Image: "openssl"})}, secrets...),
+			wantSecrets: map[string][]string{
+				"redis-restore-s3-clone":         {"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"},
+				"redis-restore-encryption-clone": {"key-1"},
+			},
+		},
+		{
+			name:    "backup on a volume",
+			source:  &middlev1alpha1.RedisFailoverSource{Backup: "other/origin-backup"},
+			objs:    append([]runtime.Object{newBackup(middlev1alpha1.RedisBackupStorage{}, nil)}, secrets...),
+			wantErr: true,
+		},
+		{
+			name:    "credentials secret missing",
+			source:  &middlev1alpha1.RedisFailoverSource{Backup: "other/origin-backup"},
+			objs:    []runtime.Object{newBackup(middlev1alpha1.RedisBackupStorage{S3: s3}, nil)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := newRestoredRedisFailover(tt.source)
+			k8sService := newFakeServices(t, tt.objs...)
+			r := RedisFailoverKubeClient{K8SService: k8sService, Logger: logr.Discard(), Record: record.NewFakeRecorder(10)}
+
+			err := r.EnsureRedisRestore(rf, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnsureRedisRestore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			job, err := k8sService.GetJob(rf.Namespace, util2.GetRedisRestoreJobName(rf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string][]string{}
+			podSpec := job.Spec.Template.Spec
+			for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+				for _, env := range container.Env {
+					if env.ValueFrom == nil || env.ValueFrom.SecretKeyRef == nil {
+						continue
+					}
+					name := env.ValueFrom.SecretKeyRef.Name
+					secret, err := k8sService.GetSecret(rf.Namespace, name)
+					if err != nil {
+						t.Fatalf("restore job reads secret %s: %v", name, err)
+					}
+					keys := []string{}
+					for key := range secret.Data {
+						keys = append(keys, key)
+					}
+					sort.Strings(keys)
+					got[name] = keys
+				}
+			}
+			if !reflect.DeepEqual(got, tt.wantSecrets) {
+				t.Errorf("secrets of the restore job = %v, want %v", got, tt.wantSecrets)
+			}
+		})
+	}
+}
+
+func TestEnsureRedisCloneBackupInAnotherNamespace(t *testing.T) {
+	s3 := &middlev1alpha1.RedisBackupS3Storage{Endpoint: "http://minio:9000", Bucket: "backups", CredentialsSecret: "s3-credentials"}
+	origin := &middlev1alpha1.RedisFailover{ObjectMeta: metav1.ObjectMeta{Name: "origin", Namespace: "other"}}
+	rf := newRestoredRedisFailover(&middlev1alpha1.RedisFailoverSource{
+		RedisFailover: "other/origin",
+		Storage:       middlev1alpha1.RedisBackupStorage{S3: s3},
+	})
+	k8sService := newFakeServices(t, origin)
+	r := RedisFailoverKubeClient{K8SService: k8sService, Logger: logr.Discard(), Record: record.NewFakeRecorder(10)}
+	ownerRefs := []metav1.OwnerReference{{Name: rf.Name, UID: rf.UID}}
+
+	if err := r.EnsureRedisRestore(rf, nil, ownerRefs); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := k8sService.GetRedisBackup("other", util2.GetCloneBackupName(rf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup.Spec.RedisFailoverName != "origin" {
+		t.Errorf("clone backup of %s, want origin", backup.Spec.RedisFailoverName)
+	}
+	if len(backup.OwnerReferences) != 0 {
+		t.Errorf("clone backup in another namespace owned by %v", backup.OwnerReferences)
+	}
+	// nothing is restored before the backup succeeded
+	if _, err := k8sService.GetJob(rf.Namespace, util2.GetRedisRestoreJobName(rf)); !errors.IsNotFound(err) {
+		t.Errorf("restore job before the backup succeeded: %v", err)
+	}
+
+	rf.Spec.Source.Storage = middlev1alpha1.RedisBackupStorage{}
+	r.K8SService = newFakeServices(t, origin)
+	if err := r.EnsureRedisRestore(rf, nil, ownerRefs); err == nil {
+		t.Error("EnsureRedisRestore() cloned another namespace without s3")
+	}
+}
+
+// newRestoredRedisFailover returns an instance named clone in the default namespace, seeded from source
+func newRestoredRedisFailover(source *middlev1alpha1.RedisFailoverSource) *middlev1alpha1.RedisFailover {
+	rf := &middlev1alpha1.RedisFailover{ObjectMeta: metav1.ObjectMeta{Name: "clone", Namespace: "default", UID: "0123456789abcdef"}}
+	rf.Spec.Source = source
+	rf.Spec.Redis.Storage.PersistentVolumeClaim = &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data"}}
+	return rf
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/client/k8s"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+)
+
+// SentinelConfigHashAnnotation records on the sentinel pods the hash of the settings of sentinel.conf
+// SENTINEL SET cannot change, the pods are restarted when it changes
+const SentinelConfigHashAnnotation = "middle.alauda.cn/sentinel-config-hash"
+
+// sentinelReportedConfigs are the settings of the master SENTINEL MASTER reports, the others can't be checked
+var sentinelReportedConfigs = map[string]bool{
+	"quorum":                  true,
+	"down-after-milliseconds": true,
+	"failover-timeout":        true,
+	"parallel-syncs":          true,
+	"notification-script":     true,
+	"client-reconfig-script":  true,
+}
+
+// getSentinelQuorum returns Sentinel.Quorum, a majority of the sentinels when unset
+func getSentinelQuorum(rf *middlev1alpha1.RedisFailover) int32 {
+	if rf.Spec.Sentinel.Quorum > 0 {
+		return rf.Spec.Sentinel.Quorum
+	}
+	return rf.Spec.Sentinel.Replicas/2 + 1
+}
+
+// getSentinelConfig returns the settings of the monitored master, from the fields of SentinelSettings and its CustomConfig
+func getSentinelConfig(rf *middlev1alpha1.RedisFailover) map[string]string {
+	sentinel := rf.Spec.Sentinel
+	config := map[string]string{}
+	for _, line := range sentinel.CustomConfig {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(fields) == 2 {
+			config[strings.ToLower(fields[0])] = strings.TrimSpace(fields[1])
+		}
+	}
+	config["quorum"] = fmt.Sprint(getSentinelQuorum(rf))
+	config["down-after-milliseconds"] = fmt.Sprint(sentinel.DownAfterMilliseconds)
+	config["failover-timeout"] = fmt.Sprint(sentinel.FailoverTimeout)
+	config["parallel-syncs"] = fmt.Sprint(sentinel.ParallelSyncs)
+	// a script can only be cleared by restarting the sentinels, see getSentinelStaticConfig
+	if sentinel.NotificationScript != "" {
+		config["notification-script"] = sentinel.NotificationScript
+	}
+	if sentinel.ClientReconfigScript != "" {
+		config["client-reconfig-script"] = sentinel.ClientReconfigScript
+	}
+	return config
+}
+
+// getSentinelStaticConfig returns the global settings of sentinel.conf. The sentinels refuse to set scripts
+// at runtime unless deny-scripts-reconfig is off, which is only done when a script is configured.
+func getSentinelStaticConfig(rf *middlev1alpha1.RedisFailover) []string {
+	if rf.Spec.Sentinel.NotificationScript == "" && rf.Spec.Sentinel.ClientReconfigScript == "" {
+		return nil
+	}
+	return []string{"sentinel deny-scripts-reconfig no"}
+}
+
+// renderSentinelConfig writes the sentinel.conf the sentinels start from, the operator points them to the master afterwards
+func renderSentinelConfig(rf *middlev1alpha1.RedisFailover) string {
+	config := getSentinelConfig(rf)
+	masterName := util2.GetSentinelMasterName(rf)
+	lines := []string{fmt.Sprintf("sentinel monitor %s 127.0.0.1 6379 %s", masterName, config["quorum"])}
+	delete(config, "quorum")
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("sentinel %s %s %s", key, masterName, config[key]))
+	}
+	// the sentinels send SLAVEOF and CONFIG REWRITE to redis under their renamed names
+	for _, rename := range rf.Spec.Redis.CustomCommandRenames {
+		if rename.To != "" {
+			lines = append(lines, fmt.Sprintf("sentinel rename-command %s %s %q", masterName, rename.From, rename.To))
+		}
+	}
+	lines = append(lines, getSentinelStaticConfig(rf)...)
+	return strings.Join(lines, "\n")
+}
+
+// getSentinelStaticConfigHash hashes the settings of sentinel.conf only a restart applies
+func getSentinelStaticConfigHash(rf *middlev1alpha1.RedisFailover) (string, error) {
+	return util2.GenerateObjectHash(getSentinelStaticConfig(rf))
+}
+
+// getSentinelGroupReplicas returns the number of sentinels monitoring the instance, read from the
+// RedisFailover running them when they are shared
+func getSentinelGroupReplicas(k8SService k8s.Services, rf *middlev1alpha1.RedisFailover) (int32, error) {
+	if rf.Spec.Sentinel.Shared == "" {
+		return rf.Spec.Sentinel.Replicas, nil
+	}
+	owner, err := k8SService.GetRedisFailover(rf.Namespace, rf.Spec.Sentinel.Shared)
+	if err != nil {
+		return 0, err
+	}
+	// fills the defaults
+	if err := owner.Validate(); err != nil {
+		return 0, fmt.Errorf("sentinels of %s: %v", owner.Name, err)
+	}
+	return owner.Spec.Sentinel.Replicas, nil
+}
+
+// listSentinelGroup returns the RedisFailovers monitored by the same sentinels as the instance, the one
+// running them included
+func listSentinelGroup(k8SService k8s.Services, rf *middlev1alpha1.RedisFailover) ([]middlev1alpha1.RedisFailover, error) {
+	owner := rf.Name
+	if rf.Spec.Sentinel.Shared != "" {
+		owner = rf.Spec.Sentinel.Shared
+	}
+	rfs, err := k8SService.ListRedisFailovers(rf.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	group := []middlev1alpha1.RedisFailover{}
+	for _, item := range rfs.Items {
+		if item.Name == owner || item.Spec.Sentinel.Shared == owner {
+			group = append(group, item)
+		}
+	}
+	return group, nil
+}
+
+func isOlderRedisFailover(rf, other *middlev1alpha1.RedisFailover) bool {
+	if rf.CreationTimestamp.Equal(&other.CreationTimestamp) {
+		return rf.Name < other.Name
+	}
+	return rf.CreationTimestamp.Before(&other.CreationTimestamp)
+}
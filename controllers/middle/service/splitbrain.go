@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/client/redis"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+)
+
+// MasterCandidate is one of the masters found after a split brain
+type MasterCandidate struct {
+	IP string
+	// Host and Port are the address sentinels and replicas know the master by
+	Host string
+	Port string
+	// RunID changes every time the redis pod restarts
+	RunID       string
+	Replication *redis.ReplicationInfo
+	// Votes is the number of sentinels answering SENTINEL get-master-addr-by-name with this master
+	Votes int
+	// RunIDVotes is the number of sentinels tracking this master by its current run ID
+	RunIDVotes int
+}
+
+// DiscardedWrites describes the replication stream a demoted master loses when it resyncs,
+// the offsets written since its own history started
+func (c MasterCandidate) DiscardedWrites() string {
+	from := c.Replication.SecondOffset
+	if from < 0 {
+		return fmt.Sprintf("offsets up to %d, all it took since it started", c.Replication.Offset)
+	}
+	return fmt.Sprintf("offsets %d to %d (%d bytes) taken since it was promoted", from, c.Replication.Offset, c.Replication.Offset-from)
+}
+
+// GetSplitBrainMaster elects the master to keep among several. The master a majority of the sentinels
+// answers SENTINEL get-master-addr-by-name with wins, otherwise the one the most sentinels track by its
+// current run ID, then the one with the highest replication offset. Sentinels that can't be reached
+// don't vote. It returns the elected master and the ones to demote.
+func (r RedisFailoverChecker) GetSplitBrainMaster(rf *v1alpha1.RedisFailover, masters []string, auth *util2.AuthConfig) (*MasterCandidate, []MasterCandidate, error) {
+	candidates := make([]MasterCandidate, 0, len(masters))
+	for _, ip := range masters {
+		host, port, err := r.GetRedisAddress(rf, ip)
+		if err != nil {
+			return nil, nil, err
+		}
+		replication, err := r.RedisClient.GetReplicationInfo(ip, auth)
+		if err != nil {
+			return nil, nil, err
+		}
+		server, err := r.RedisClient.GetServerInfo(ip, auth)
+		if err != nil {
+			return nil, nil, err
+		}
+		candidates = append(candidates, MasterCandidate{IP: ip, Host: host, Port: port, RunID: server.RunID, Replication: replication})
+	}
+
+	sentinels, err := r.GetSentinelsIPs(rf)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, sip := range sentinels {
+		host, port, err := r.RedisClient.GetSentinelMasterAddress(sip, auth)
+		if err != nil {
+			r.Logger.Error(err, "sentinel does not vote for a master", "sentinel", sip)
+			continue
+		}
+		config, err := r.RedisClient.GetSentinelMasterConfig(sip, auth)
+		if err != nil {
+			r.Logger.Error(err, "sentinel does not vote for a master", "sentinel", sip)
+			continue
+		}
+		for i := range candidates {
+			if candidates[i].Host == host && candidates[i].Port == port {
+				candidates[i].Votes++
+			}
+			if candidates[i].RunID == config["runid"] {
+				candidates[i].RunIDVotes++
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		majority := len(sentinels)/2 + 1
+		if (a.Votes >= majority) != (b.Votes >= majority) {
+			return a.Votes >= majority
+		}
+		if a.RunIDVotes != b.RunIDVotes {
+			return a.RunIDVotes > b.RunIDVotes
+		}
+		if a.Replication.Offset != b.Replication.Offset {
+			return a.Replication.Offset > b.Replication.Offset
+		}
+		return a.IP < b.IP
+	})
+	return &candidates[0], candidates[1:], nil
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"github.com/DevineLiu/redis-operator/controllers/middle/client/redis"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// fakeRedisClient answers from its maps by IP, the calls it does not implement panic
+type fakeRedisClient struct {
+	redis.Client
+	replication map[string]*redis.ReplicationInfo
+	runIDs      map[string]string
+	// sentinelMasters and sentinelRunIDs are what the sentinels answer, a sentinel missing from
+	// sentinelMasters can't be reached
+	sentinelMasters map[string]string
+	sentinelRunIDs  map[string]string
+	// master is the IP MakeMaster was called on
+	master string
+}
+
+func (c *fakeRedisClient) GetReplicationInfo(ip string, auth *util2.AuthConfig) (*redis.ReplicationInfo, error) {
+	replication, ok := c.replication[ip]
+	if !ok {
+		return nil, fmt.Errorf("redis %s unreachable", ip)
+	}
+	return replication, nil
+}
+
+func (c *fakeRedisClient) GetServerInfo(ip string, auth *util2.AuthConfig) (*redis.ServerInfo, error) {
+	return &redis.ServerInfo{RunID: c.runIDs[ip], Version: "5.0.4"}, nil
+}
+
+func (c *fakeRedisClient) GetSentinelMasterAddress(ip string, auth *util2.AuthConfig) (string, string, error) {
+	master, ok := c.sentinelMasters[ip]
+	if !ok {
+		return "", "", errors.New("sentinel unreachable")
+	}
+	return master, redisPort, nil
+}
+
+func (c *fakeRedisClient) GetSentinelMasterConfig(ip string, auth *util2.AuthConfig) (map[string]string, error) {
+	return map[string]string{"runid": c.sentinelRunIDs[ip]}, nil
+}
+
+// newSentinelObjects returns the sentinel Deployment of the instance and a running pod of it per IP, created a minute apart
+func newSentinelObjects(rf *middlev1alpha1.RedisFailover, ips ...string) []runtime.Object {
+	return newWorkloadObjects(rf, util2.GetSentinelName(rf), util2.SentinelRoleName, &appsv1.Deployment{}, ips)
+}
+
+func newWorkloadObjects(rf *middlev1alpha1.RedisFailover, name, component string, workload runtime.Object, ips []string) []runtime.Object {
+	labels := generateSelectorLabels(component, rf.Name)
+	meta := metav1.ObjectMeta{Name: name, Namespace: rf.Namespace}
+	selector := &metav1.LabelSelector{MatchLabels: labels}
+	switch w := workload.(type) {
+	case *appsv1.StatefulSet:
+		w.ObjectMeta, w.Spec.Selector = meta, selector
+	case *appsv1.Deployment:
+		w.ObjectMeta, w.Spec.Selector = meta, selector
+	}
+	objs := []runtime.Object{workload}
+	start := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	for i, ip := range ips {
+		objs = append(objs, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              fmt.Sprintf("%s-%d", name, i),
+				Namespace:         rf.Namespace,
+				Labels:            labels,
+				CreationTimestamp: metav1.NewTime(start.Add(time.Duration(i) * time.Minute)),
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: ip},
+		})
+	}
+	return objs
+}
+
+func TestGetSplitBrainMaster(t *testing.T) {
+	sentinels := []string{"10.0.1.1", "10.0.1.2", "10.0.1.3"}
+	tests := []struct {
+		name        string
+		offsets     map[string]int64
+		votes       map[string]string
+		runIDVotes  map[string]string
+		wantMaster  string
+		wantDemoted []string
+	}{
+		{
+			name:        "sentinel majority over the offset",
+			offsets:     map[string]int64{"10.0.0.1": 100, "10.0.0.2": 50},
+			votes:       map[string]string{"10.0.1.1": "10.0.0.2", "10.0.1.2": "10.0.0.2", "10.0.1.3": "10.0.0.1"},
+			runIDVotes:  map[string]string{"10.0.1.1": "run-10.0.0.1", "10.0.1.2": "run-10.0.0.1", "10.0.1.3": "run-10.0.0.1"},
+			wantMaster:  "10.0.0.2",
+			wantDemoted: []string{"10.0.0.1"},
+		},
+		{
+			name:        "run ID votes without a majority",
+			offsets:     map[string]int64{"10.0.0.1": 100, "10.0.0.2": 50},
+			votes:       map[string]string{"10.0.1.1": "10.0.0.1", "10.0.1.2": "10.0.0.2"},
+			runIDVotes:  map[string]string{"10.0.1.1": "run-10.0.0.2", "10.0.1.2": "run-10.0.0.2"},
+			wantMaster:  "10.0.0.2",
+			wantDemoted: []string{"10.0.0.1"},
+		},
+		{
+			name:        "an unreachable majority does not vote",
+			offsets:     map[string]int64{"10.0.0.1": 50, "10.0.0.2": 100},
+			votes:       map[string]string{"10.0.1.1": "10.0.0.1"},
+			runIDVotes:  map[string]string{"10.0.1.1": "stale"},
+			wantMaster:  "10.0.0.2",
+			wantDemoted: []string{"10.0.0.1"},
+		},
+		{
+			name:        "highest offset without votes",
+			offsets:     map[string]int64{"10.0.0.1": 10, "10.0.0.2": 30, "10.0.0.3": 20},
+			wantMaster:  "10.0.0.2",
+			wantDemoted: []string{"10.0.0.3", "10.0.0.1"},
+		},
+		{
+			name:        "IP breaks ties",
+			offsets:     map[string]int64{"10.0.0.2": 10, "10.0.0.1": 10},
+			wantMaster:  "10.0.0.1",
+			wantDemoted: []string{"10.0.0.2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &middlev1alpha1.RedisFailover{ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default"}}
+			redisClient := &fakeRedisClient{
+				replication:     map[string]*redis.ReplicationInfo{},
+				runIDs:          map[string]string{},
+				sentinelMasters: tt.votes,
+				sentinelRunIDs:  tt.runIDVotes,
+			}
+			masters := []string{}
+			for ip, offset := range tt.offsets {
+				masters = append(masters, ip)
+				redisClient.replication[ip] = &redis.ReplicationInfo{Master: true, Offset: offset}
+				redisClient.runIDs[ip] = "run-" + ip
+			}
+			checker := RedisFailoverChecker{
+				K8SService:  newFakeServices(t, newSentinelObjects(rf, sentinels...)...),
+				Logger:      logr.Discard(),
+				RedisClient: redisClient,
+			}
+
+			master, demoted, err := checker.GetSplitBrainMaster(rf, masters, &util2.AuthConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			demotedIPs := []string{}
+			for _, candidate := range demoted {
+				demotedIPs = append(demotedIPs, candidate.IP)
+			}
+			if master.IP != tt.wantMaster || !reflect.DeepEqual(demotedIPs, tt.wantDemoted) {
+				t.Errorf("GetSplitBrainMaster() = %s, %v, want %s, %v", master.IP, demotedIPs, tt.wantMaster, tt.wantDemoted)
+			}
+		})
+	}
+}
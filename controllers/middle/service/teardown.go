@@ -0,0 +1,145 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	util2 "github.com/DevineLiu/redis-operator/controllers/util"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EnsureRedisFinalBackup requests the backup taken before the instance is deleted. It carries no owner
+// reference so it outlives the instance.
+func (r RedisFailoverKubeClient) EnsureRedisFinalBackup(rf *middlev1alpha1.RedisFailover) (*middlev1alpha1.RedisBackup, error) {
+	backup, err := r.K8SService.GetRedisBackup(rf.Namespace, util2.GetFinalBackupName(rf))
+	if err == nil {
+		return backup, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	backup = &middlev1alpha1.RedisBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util2.GetFinalBackupName(rf),
+			Namespace: rf.Namespace,
+			Labels: map[string]string{
+				"redis/managed-by":       "redis-operator",
+				BackupRedisFailoverLabel: rf.Name,
+			},
+		},
+		Spec: middlev1alpha1.RedisBackupSpec{
+			RedisFailoverName: rf.Name,
+			Image:             rf.Spec.Redis.Backup.Image,
+			Storage:           *rf.Spec.Redis.Backup.FinalBackup,
+			Encryption:        rf.Spec.Redis.Backup.Encryption,
+		},
+	}
+	if err := r.K8SService.CreateRedisBackup(rf.Namespace, backup); err != nil && !errors.IsAlreadyExists(err) {
+		return nil, err
+	}
+	return backup, nil
+}
+
+// EnsureSentinelStopped scales the sentinels down so no failover happens while redis is stopped,
+// it reports whether all the sentinel pods are gone. Shared sentinels are left to the RedisFailover running
+// them, which keeps them until the other instances they monitor are deleted.
+func (r RedisFailoverKubeClient) EnsureSentinelStopped(rf *middlev1alpha1.RedisFailover) (bool, error) {
+	if rf.Spec.Sentinel.Shared != "" {
+		return true, nil
+	}
+	group, err := listSentinelGroup(r.K8SService, rf)
+	if err != nil {
+		return false, err
+	}
+	for i := range group {
+		if group[i].Name != rf.Name {
+			return false, nil
+		}
+	}
+	deploy, err := r.K8SService.GetDeployment(rf.Namespace, util2.GetSentinelName(rf))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	if deploy.Spec.Replicas == nil || *deploy.Spec.Replicas != 0 {
+		replicas := int32(0)
+		deploy.Spec.Replicas = &replicas
+		if err := r.K8SService.UpdateDeployment(rf.Namespace, deploy); err != nil {
+			return false, err
+		}
+	}
+	pods, err := r.K8SService.GetDeploymentPods(rf.Namespace, util2.GetSentinelName(rf))
+	if err != nil {
+		return false, err
+	}
+	return len(pods.Items) == 0, nil
+}
+
+// EnsureRedisStopped scales the redis StatefulSet down to zero, its pods are stopped one at a time from
+// the last ordinal, and reports whether all the redis pods are gone
+func (r RedisFailoverKubeClient) EnsureRedisStopped(rf *middlev1alpha1.RedisFailover) (bool, error) {
+	ss, err := r.K8SService.GetStatefulSet(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	if ss.Spec.Replicas == nil || *ss.Spec.Replicas != 0 {
+		replicas := int32(0)
+		ss.Spec.Replicas = &replicas
+		if err := r.K8SService.UpdateStatefulSet(rf.Namespace, ss); err != nil {
+			return false, err
+		}
+	}
+	pods, err := r.K8SService.GetStatefulSetPods(rf.Namespace, util2.GetRedisName(rf))
+	if err != nil {
+		return false, err
+	}
+	return len(pods.Items) == 0, nil
+}
+
+// EnsureRedisVolumesReleased orphans the redis volumes when Storage.KeepAfterDeletion is set, and
+// deletes them otherwise
+func (r RedisFailoverKubeClient) EnsureRedisVolumesReleased(rf *middlev1alpha1.RedisFailover) error {
+	if rf.Spec.Redis.Storage.PersistentVolumeClaim == nil {
+		return nil
+	}
+	// the claims created by the StatefulSet only carry the labels of its template, so they are
+	// matched by the <template>-<statefulset>- prefix of their names
+	prefix := fmt.Sprintf("%s-%s-", rf.Spec.Redis.Storage.PersistentVolumeClaim.Name, util2.GetRedisName(rf))
+	pvcs, err := r.K8SService.ListPersistentVolumeClaims(rf.Namespace, nil)
+	if err != nil {
+		return err
+	}
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		if !strings.HasPrefix(pvc.Name, prefix) {
+			continue
+		}
+		if !rf.Spec.Redis.Storage.KeepAfterDeletion {
+			if err := r.K8SService.DeletePersistentVolumeClaim(rf.Namespace, pvc.Name); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			continue
+		}
+		ownerRefs := []metav1.OwnerReference{}
+		for _, ref := range pvc.OwnerReferences {
+			if ref.UID != rf.UID {
+				ownerRefs = append(ownerRefs, ref)
+			}
+		}
+		if len(ownerRefs) == len(pvc.OwnerReferences) {
+			continue
+		}
+		pvc.OwnerReferences = ownerRefs
+		if err := r.K8SService.UpdatePersistentVolumeClaim(rf.Namespace, pvc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
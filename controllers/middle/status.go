@@ -0,0 +1,74 @@
+package middle
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// statusPatcher writes the status changes made during a reconcile as a single merge patch. It keeps
+// the object as it was read, the patch is skipped when the status did not change.
+type statusPatcher struct {
+	client   client.Client
+	original client.Object
+}
+
+func newStatusPatcher(c client.Client, obj client.Object) *statusPatcher {
+	return &statusPatcher{client: c, original: obj.DeepCopyObject().(client.Object)}
+}
+
+// Patch writes the status of obj. The patch carries the resourceVersion it was computed against, on
+// a conflict it is computed again against the latest object, the status of obj overriding its own.
+// An object deleted meanwhile is not an error.
+func (p *statusPatcher) Patch(ctx context.Context, obj client.Object) error {
+	changed, err := statusChanged(p.original, obj)
+	if err != nil || !changed {
+		return err
+	}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := p.client.Status().Patch(ctx, obj, client.MergeFromWithOptions(p.original, client.MergeFromWithOptimisticLock{}))
+		if !errors.IsConflict(err) {
+			return err
+		}
+		latest := obj.DeepCopyObject().(client.Object)
+		if err := p.client.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, latest); err != nil {
+			return err
+		}
+		p.original = latest
+		obj.SetResourceVersion(latest.GetResourceVersion())
+		return err
+	})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func statusChanged(original, obj client.Object) (bool, error) {
+	originalStatus, err := getStatus(original)
+	if err != nil {
+		return false, err
+	}
+	status, err := getStatus(obj)
+	if err != nil {
+		return false, err
+	}
+	return !reflect.DeepEqual(originalStatus, status), nil
+}
+
+func getStatus(obj client.Object) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields["status"], nil
+}
@@ -0,0 +1,140 @@
+package middle
+
+import (
+	"context"
+	"testing"
+
+	middlev1alpha1 "github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// countingClient counts the status patches sent through it
+type countingClient struct {
+	client.Client
+	patches int
+}
+
+func (c *countingClient) Status() client.StatusWriter {
+	return &countingStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type countingStatusWriter struct {
+	client.StatusWriter
+	client *countingClient
+}
+
+func (w *countingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	w.client.patches++
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+func TestStatusPatcher(t *testing.T) {
+	tests := []struct {
+		name string
+		// change is made to the object the reconcile read
+		change func(rf *middlev1alpha1.RedisFailover)
+		// concurrent is made by another writer once the object was read
+		concurrent  func(rf *middlev1alpha1.RedisFailover)
+		deleted     bool
+		wantPatches int
+		wantPhase   middlev1alpha1.Phase
+	}{
+		{
+			name:        "no-op skipped",
+			change:      func(rf *middlev1alpha1.RedisFailover) {},
+			wantPatches: 0,
+			wantPhase:   middlev1alpha1.RedisFailoverPhaseCreating,
+		},
+		{
+			name:        "spec change alone skipped",
+			change:      func(rf *middlev1alpha1.RedisFailover) { rf.Spec.Redis.Replicas = 5 },
+			wantPatches: 0,
+			wantPhase:   middlev1alpha1.RedisFailoverPhaseCreating,
+		},
+		{
+			name:        "status written",
+			change:      func(rf *middlev1alpha1.RedisFailover) { rf.Status.Phase = middlev1alpha1.RedisFailoverPhaseReady },
+			wantPatches: 1,
+			wantPhase:   middlev1alpha1.RedisFailoverPhaseReady,
+		},
+		{
+			name:        "conflict retried on the latest object",
+			change:      func(rf *middlev1alpha1.RedisFailover) { rf.Status.Phase = middlev1alpha1.RedisFailoverPhaseReady },
+			concurrent:  func(rf *middlev1alpha1.RedisFailover) { rf.Spec.Redis.Replicas = 5 },
+			wantPatches: 2,
+			wantPhase:   middlev1alpha1.RedisFailoverPhaseReady,
+		},
+		{
+			name:   "conflict on the status, the reconcile wins the fields it changed",
+			change: func(rf *middlev1alpha1.RedisFailover) { rf.Status.Phase = middlev1alpha1.RedisFailoverPhaseReady },
+			concurrent: func(rf *middlev1alpha1.RedisFailover) {
+				rf.Status.Phase = middlev1alpha1.RedisFailoverPhaseFailed
+				rf.Status.Version = "5.0.4"
+			},
+			wantPatches: 2,
+			wantPhase:   middlev1alpha1.RedisFailoverPhaseReady,
+		},
+		{
+			name:        "object deleted meanwhile",
+			change:      func(rf *middlev1alpha1.RedisFailover) { rf.Status.Phase = middlev1alpha1.RedisFailoverPhaseReady },
+			deleted:     true,
+			wantPatches: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.TODO()
+			scheme := runtime.NewScheme()
+			if err := middlev1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			stored := &middlev1alpha1.RedisFailover{}
+			stored.Name, stored.Namespace = "redis", "default"
+			stored.Status.Phase = middlev1alpha1.RedisFailoverPhaseCreating
+			c := &countingClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(stored).Build()}
+			key := types.NamespacedName{Namespace: "default", Name: "redis"}
+
+			rf := &middlev1alpha1.RedisFailover{}
+			if err := c.Get(ctx, key, rf); err != nil {
+				t.Fatal(err)
+			}
+			patcher := newStatusPatcher(c, rf)
+			if tt.concurrent != nil {
+				other := &middlev1alpha1.RedisFailover{}
+				if err := c.Get(ctx, key, other); err != nil {
+					t.Fatal(err)
+				}
+				tt.concurrent(other)
+				if err := c.Update(ctx, other); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.deleted {
+				if err := c.Delete(ctx, rf.DeepCopy()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tt.change(rf)
+
+			if err := patcher.Patch(ctx, rf); err != nil {
+				t.Fatalf("Patch() error = %v", err)
+			}
+			if c.patches != tt.wantPatches {
+				t.Errorf("Patch() sent %d patches, want %d", c.patches, tt.wantPatches)
+			}
+			if tt.deleted {
+				return
+			}
+			latest := &middlev1alpha1.RedisFailover{}
+			if err := c.Get(ctx, key, latest); err != nil {
+				t.Fatal(err)
+			}
+			if latest.Status.Phase != tt.wantPhase {
+				t.Errorf("stored phase %q, want %q", latest.Status.Phase, tt.wantPhase)
+			}
+		})
+	}
+}
@@ -1,5 +1,77 @@
 package util
 
+import (
+	"strings"
+
+	"github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+)
+
+// DefaultSentinelMasterName is the master name of the instances running their own sentinels
+const DefaultSentinelMasterName = "mymaster"
+
 type AuthConfig struct {
 	Password string
+	// CommandRenames maps the lower case name of a redis command to the name redis.conf renamed it to
+	CommandRenames map[string]string
+	// PodCommandRenames maps the IP of a redis pod to the renames it was started with, CommandRenames
+	// applies to the pods left out
+	PodCommandRenames map[string]map[string]string
+	// MasterName the sentinels monitor the master under
+	MasterName string
+	// SentinelCommandRenames are the renames the sentinels send the commands of a failover with
+	SentinelCommandRenames map[string]string
+}
+
+// Command returns the name the redis pods know the command by
+func (a *AuthConfig) Command(name string) string {
+	if renamed, ok := a.CommandRenames[strings.ToLower(name)]; ok {
+		return renamed
+	}
+	return name
+}
+
+// ForRedis returns the auth to talk to the redis pod with the given IP with
+func (a *AuthConfig) ForRedis(ip string) *AuthConfig {
+	renames, ok := a.PodCommandRenames[ip]
+	if !ok {
+		return a
+	}
+	podAuth := *a
+	podAuth.CommandRenames = renames
+	return &podAuth
+}
+
+// GetSentinelMasterName returns the name the sentinels monitor the master of the instance under
+func GetSentinelMasterName(rf *v1alpha1.RedisFailover) string {
+	switch {
+	case rf.Spec.Sentinel.MasterName != "":
+		return rf.Spec.Sentinel.MasterName
+	case rf.Spec.Sentinel.Shared != "":
+		return rf.Name
+	default:
+		return DefaultSentinelMasterName
+	}
+}
+
+// GetRedisCommandRenames returns the renames of Redis.CustomCommandRenames by lower case command name
+func GetRedisCommandRenames(rf *v1alpha1.RedisFailover) map[string]string {
+	if len(rf.Spec.Redis.CustomCommandRenames) == 0 {
+		return nil
+	}
+	renames := map[string]string{}
+	for _, rename := range rf.Spec.Redis.CustomCommandRenames {
+		renames[strings.ToLower(rename.From)] = rename.To
+	}
+	return renames
+}
+
+// GetSentinelCommandRenames returns the renames of commands the sentinels can send, the disabled ones left out
+func GetSentinelCommandRenames(renames map[string]string) map[string]string {
+	sentinelRenames := map[string]string{}
+	for from, to := range renames {
+		if to != "" {
+			sentinelRenames[from] = to
+		}
+	}
+	return sentinelRenames
 }
@@ -2,6 +2,11 @@ package util
 
 import (
 	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
 )
 
@@ -18,6 +23,11 @@ const (
 	RedisName              = "-redisdb"
 	RedisShutdownName      = "r-s"
 	RedisRoleName          = "redis"
+	BackupName             = "-backup"
+	BackupRoleName         = "backup"
+	BackupFileName         = "dump.rdb"
+	RestoreName            = "-restore"
+	EncryptedFileSuffix    = ".enc"
 	AppLabel               = "redis-failover"
 	HostnameTopologyKey    = "kubernetes.io/hostname"
 )
@@ -38,6 +48,15 @@ func GetSentinelName(rf *v1alpha1.RedisFailover) string {
 	return GenerateName(SentinelName, rf.Name)
 }
 
+// GetSentinelGroupName returns the name of the sentinels monitoring the instance, those of the
+// RedisFailover of Sentinel.Shared when they are shared
+func GetSentinelGroupName(rf *v1alpha1.RedisFailover) string {
+	if rf.Spec.Sentinel.Shared != "" {
+		return GenerateName(SentinelName, rf.Spec.Sentinel.Shared)
+	}
+	return GetSentinelName(rf)
+}
+
 func GetRedisProxyName(rp *v1alpha1.RedisProxy) string {
 	return  GenerateProxyName(ProxyName,rp.Name)
 }
@@ -55,18 +74,100 @@ func GetSentinelHeadlessSvc(rf *v1alpha1.RedisFailover) string {
 	return GenerateName("-sentinel-headless", rf.Name)
 }
 
-func GetRedisNodePortSvc(rf *v1alpha1.RedisFailover) string {
-	return GenerateName("-redis-node-port", rf.Name)
+// GetRedisExposeName returns the name of the redis pod of the given ordinal, and of the Service exposing it
+func GetRedisExposeName(rf *v1alpha1.RedisFailover, ordinal int) string {
+	return fmt.Sprintf("%s-%d", GetRedisName(rf), ordinal)
+}
+
+// GetSentinelExposeName returns the name of the Service exposing the sentinel of the given index
+func GetSentinelExposeName(rf *v1alpha1.RedisFailover, index int) string {
+	return fmt.Sprintf("%s-%d", GetSentinelName(rf), index)
 }
 
 func GetRedisSecretName(rf *v1alpha1.RedisFailover) string {
 	return GenerateName("-passwd-readonly", rf.Name)
 }
 
+func GetBackupJobName(b *v1alpha1.RedisBackup) string {
+	return GenerateName(BackupName, b.Name)
+}
+
+func GetBackupPersistentVolumeClaimName(b *v1alpha1.RedisBackup) string {
+	return GenerateName(BackupName, b.Name)
+}
+
+// GetBackupObjectKey returns the key of the backup object in its S3 bucket
+func GetBackupObjectKey(b *v1alpha1.RedisBackup) string {
+	startTime := b.CreationTimestamp.Time
+	if b.Status.StartTime != nil {
+		startTime = b.Status.StartTime.Time
+	}
+	return path.Join(b.Spec.Storage.S3.Prefix, b.Namespace, b.Name, startTime.UTC().Format("20060102T150405Z"), GetBackupFileName(b))
+}
+
+// GetBackupFileName returns the name of the backup artifact, encrypted backups are suffixed with .enc
+func GetBackupFileName(b *v1alpha1.RedisBackup) string {
+	if b.Spec.Encryption != nil {
+		return BackupFileName + EncryptedFileSuffix
+	}
+	return BackupFileName
+}
+
+func GetRedisRestoreJobName(rf *v1alpha1.RedisFailover) string {
+	return GenerateName(RestoreName, rf.Name)
+}
+
+// GetCloneBackupName returns the name of the RedisBackup taken of the instance a RedisFailover is cloned from,
+// suffixed with the UID of the clone since it sits in the namespace of the source
+func GetCloneBackupName(rf *v1alpha1.RedisFailover) string {
+	return appendUIDSuffix(rf.Name+"-clone", rf)
+}
+
+// GetFinalBackupName returns the name of the RedisBackup taken before the instance is deleted,
+// suffixed with its UID since the backup outlives it
+func GetFinalBackupName(rf *v1alpha1.RedisFailover) string {
+	return appendUIDSuffix(rf.Name+"-final", rf)
+}
+
+// appendUIDSuffix suffixes name with the first 8 characters of the UID of the instance, with the whole of a
+// shorter one and with nothing when the instance has no UID yet
+func appendUIDSuffix(name string, rf *v1alpha1.RedisFailover) string {
+	uid := string(rf.UID)
+	if len(uid) > 8 {
+		uid = uid[:8]
+	}
+	if uid == "" {
+		return name
+	}
+	return fmt.Sprintf("%s-%s", name, uid)
+}
+
+// GetRedisRestoreSecretName returns the name of the copy of a Secret a restore from another namespace needs
+func GetRedisRestoreSecretName(rf *v1alpha1.RedisFailover, kind string) string {
+	return GenerateName(fmt.Sprintf("%s-%s", RestoreName, kind), rf.Name)
+}
+
+// SplitNamespacedName splits a namespace/name reference, a bare name lives in defaultNamespace
+func SplitNamespacedName(ref, defaultNamespace string) (string, string) {
+	if parts := strings.SplitN(ref, "/", 2); len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return defaultNamespace, ref
+}
+
+func GetScheduledBackupName(rf *v1alpha1.RedisFailover, schedule string, scheduledAt time.Time) string {
+	return fmt.Sprintf("%s-%s-%d", rf.Name, schedule, scheduledAt.Unix()/60)
+}
+
 func GetSentinelReadinessConfigmap(rf *v1alpha1.RedisFailover) string {
 	return GenerateName("-sentinel-readiness", rf.Name)
 }
 
+// GetRedisConfigMapName returns the name of the ConfigMap holding the redis.conf the operator renders
+func GetRedisConfigMapName(rf *v1alpha1.RedisFailover) string {
+	return GenerateName(RedisName+"-config", rf.Name)
+}
+
 func GetRedisShutdownConfigMapName(rf *v1alpha1.RedisFailover) string {
 	if rf.Spec.Redis.ShutdownConfigMap != "" {
 		return rf.Spec.Redis.ShutdownConfigMap
@@ -75,3 +176,16 @@ func GetRedisShutdownConfigMapName(rf *v1alpha1.RedisFailover) string {
 }
 
 
+
+// GetPodOrdinal returns the ordinal of a StatefulSet pod from its name, -1 if it has none
+func GetPodOrdinal(name string) int {
+	i := strings.LastIndex(name, "-")
+	if i == -1 {
+		return -1
+	}
+	ordinal, err := strconv.Atoi(name[i+1:])
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
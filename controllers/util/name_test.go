@@ -0,0 +1,49 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestGetFinalBackupName(t *testing.T) {
+	tests := []struct {
+		name string
+		uid  string
+		want string
+	}{
+		{name: "uid", uid: "0123abcd-4567-89ef-0123-456789abcdef", want: "redis-final-0123abcd"},
+		{name: "short uid", uid: "0123", want: "redis-final-0123"},
+		{name: "no uid", want: "redis-final"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &v1alpha1.RedisFailover{ObjectMeta: metav1.ObjectMeta{Name: "redis", UID: types.UID(tt.uid)}}
+			if got := GetFinalBackupName(rf); got != tt.want {
+				t.Errorf("GetFinalBackupName() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCloneBackupName(t *testing.T) {
+	tests := []struct {
+		name string
+		uid  string
+		want string
+	}{
+		{name: "uid", uid: "0123abcd-4567-89ef-0123-456789abcdef", want: "redis-clone-0123abcd"},
+		{name: "short uid", uid: "0123", want: "redis-clone-0123"},
+		{name: "no uid", want: "redis-clone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := &v1alpha1.RedisFailover{ObjectMeta: metav1.ObjectMeta{Name: "redis", UID: types.UID(tt.uid)}}
+			if got := GetCloneBackupName(rf); got != tt.want {
+				t.Errorf("GetCloneBackupName() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package util
+
+import (
+	"github.com/DevineLiu/redis-operator/apis/middle/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// S3CredentialKeys are the keys of the Secret named by the credentialsSecret of an s3 storage
+var S3CredentialKeys = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"}
+
+// GenerateS3Env returns the environment the aws cli reaches the given s3 storage with
+func GenerateS3Env(s3 *v1alpha1.RedisBackupS3Storage) []corev1.EnvVar {
+	env := []corev1.EnvVar{}
+	for _, key := range S3CredentialKeys {
+		env = append(env, corev1.EnvVar{
+			Name: key,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: s3.CredentialsSecret,
+					},
+					Key: key,
+				},
+			},
+		})
+	}
+	return append(env, corev1.EnvVar{
+		Name:  "AWS_DEFAULT_REGION",
+		Value: s3.Region,
+	})
+}
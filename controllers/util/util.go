@@ -1,8 +1,15 @@
 package util
 
 import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"strconv"
 	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
 )
 
 func ParseRedisMemConf(p string) (string, error) {
@@ -40,3 +47,35 @@ func ParseRedisMemConf(p string) (string, error) {
 
 	return strconv.FormatInt(val*mul, 10), nil
 }
+
+// OwnerReferencesEqual reports whether both lists reference the same owners
+func OwnerReferencesEqual(a, b []metav1.OwnerReference) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].UID != b[i].UID {
+			return false
+		}
+	}
+	return true
+}
+
+// GenerateObjectHash returns a short hash of the JSON encoding of obj, suitable for an annotation value
+func GenerateObjectHash(obj interface{}) (string, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	hasher := fnv.New32a()
+	hasher.Write(data)
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32())), nil
+}
+
+// PullPolicy returns the pull policy of a spec, Always when it sets none
+func PullPolicy(specPolicy corev1.PullPolicy) corev1.PullPolicy {
+	if specPolicy == "" {
+		return corev1.PullAlways
+	}
+	return specPolicy
+}
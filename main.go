@@ -78,21 +78,22 @@ func main() {
 		os.Exit(1)
 	}
 
-	// if err = (&controllers.RedisFailoverReconciler{
-	// 	Client: mgr.GetClient(),
-	// 	Scheme: mgr.GetScheme(),
-	// 	Logger: mgr.GetLogger(),
-	// }).SetupWithManager(mgr); err != nil {
-	// 	setupLog.Error(err, "unable to create controller", "controller", "RedisFailover")
-	// 	os.Exit(1)
-	// }
-	// if err = (&controllers.RedisBackupReconciler{
-	// 	Client: mgr.GetClient(),
-	// 	Scheme: mgr.GetScheme(),
-	// }).SetupWithManager(mgr); err != nil {
-	// 	setupLog.Error(err, "unable to create controller", "controller", "RedisBackup")
-	// 	os.Exit(1)
-	// }
+	if err = (&controllers.RedisFailoverReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Logger: mgr.GetLogger(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RedisFailover")
+		os.Exit(1)
+	}
+	if err = (&controllers.RedisBackupReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Logger: mgr.GetLogger(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RedisBackup")
+		os.Exit(1)
+	}
 	if err = (&controllers.RedisProxyReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),